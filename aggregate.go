@@ -0,0 +1,229 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Number is the constraint of the fields Sum and Avg accept
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Selector is any Field of T, whatever the type of its values.
+type Selector[T any] interface {
+	value(r *row[T]) (any, error)
+}
+
+// Aggregate is the typed badgerhold.AggregateResult of a group of records of T sharing the value G of a field.
+type Aggregate[T, G any] struct {
+	group     G
+	reduction []T
+}
+
+// FindAggregateBy groups the records of T matching query by the value of field.
+func FindAggregateBy[T, G any](s Store[T], query *badgerhold.Query, field Field[T, G]) ([]*Aggregate[T, G], error) {
+	var result []*Aggregate[T, G]
+	err := s.View(func(tx Tx[T]) error {
+		var err error
+		result, err = TxFindAggregateBy(s, tx.Txn(), query, field)
+		return err
+	})
+	return result, err
+}
+
+func TxFindAggregateBy[T, G any](s Store[T], tx *badger.Txn, query *badgerhold.Query, field Field[T, G]) ([]*Aggregate[T, G], error) {
+	if err := checkFieldType(field); err != nil {
+		return nil, err
+	}
+
+	results, err := s.TxFindAggregate(tx, query, string(field))
+	if err != nil {
+		return nil, err
+	}
+
+	aggregates := make([]*Aggregate[T, G], len(results))
+	for i, result := range results {
+		aggregates[i] = &Aggregate[T, G]{}
+		result.Group(&aggregates[i].group)
+		result.Reduction(&aggregates[i].reduction)
+	}
+	return aggregates, nil
+}
+
+// Group is the field value shared by the records of the group
+func (a *Aggregate[T, G]) Group() G {
+	return a.group
+}
+
+// Reduction is the records of the group
+func (a *Aggregate[T, G]) Reduction() []T {
+	return a.reduction
+}
+
+func (a *Aggregate[T, G]) Count() uint64 {
+	return uint64(len(a.reduction))
+}
+
+// Sort sorts the reduction by field, as badgerhold compares values.
+// It returns an error, leaving the reduction as it is, when T has no such field.
+func (a *Aggregate[T, G]) Sort(field Selector[T]) error {
+	values, err := a.values(field)
+	if err != nil {
+		return err
+	}
+	sort.Stable(&reductionSort[T]{reduction: a.reduction, values: values})
+	return nil
+}
+
+// Min returns the record of the group with the lowest field value, the zero T if the group is empty.
+// It returns an error when T has no such field.
+func (a *Aggregate[T, G]) Min(field Selector[T]) (T, error) {
+	return a.pick(field, func(c int) bool { return c < 0 })
+}
+
+// Max returns the record of the group with the highest field value, the zero T if the group is empty.
+// It returns an error when T has no such field.
+func (a *Aggregate[T, G]) Max(field Selector[T]) (T, error) {
+	return a.pick(field, func(c int) bool { return c > 0 })
+}
+
+// Sum adds up field over the records of the group.
+// It returns an error when T has no such field of type N.
+func Sum[T, G any, N Number](a *Aggregate[T, G], field Field[T, N]) (N, error) {
+	var sum N
+	values, err := numbers(a, field)
+	for _, value := range values {
+		sum += value
+	}
+	return sum, err
+}
+
+// Avg is the mean of field over the records of the group, 0 if the group is empty.
+// It returns an error when T has no such field of type N.
+func Avg[T, G any, N Number](a *Aggregate[T, G], field Field[T, N]) (float64, error) {
+	values, err := numbers(a, field)
+	if err != nil || len(values) == 0 {
+		return 0, err
+	}
+
+	var sum float64
+	for _, value := range values {
+		sum += float64(value)
+	}
+	return sum / float64(len(values)), nil
+}
+
+// numbers reads field out of every record of the group
+func numbers[T, G any, N Number](a *Aggregate[T, G], field Field[T, N]) ([]N, error) {
+	err := checkFieldType(field)
+	if err != nil {
+		return nil, err
+	}
+	values, err := a.values(field)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]N, len(values))
+	for i, value := range values {
+		result[i], err = typed[N](value, result[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (a *Aggregate[T, G]) pick(field Selector[T], better func(c int) bool) (T, error) {
+	var result T
+	values, err := a.values(field)
+	if err != nil {
+		return result, err
+	}
+	best := -1
+	for i := range values {
+		if best < 0 {
+			best = i
+			continue
+		}
+		c, err := compare(values[i], values[best])
+		if err == nil && better(c) {
+			best = i
+		}
+	}
+
+	if best >= 0 {
+		result = a.reduction[best]
+	}
+	return result, nil
+}
+
+// values reads field out of every record of the group
+func (a *Aggregate[T, G]) values(field Selector[T]) ([]any, error) {
+	values := make([]any, len(a.reduction))
+	for i := range a.reduction {
+		value, err := field.value(&row[T]{record: &a.reduction[i]})
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+type reductionSort[T any] struct {
+	reduction []T
+	values    []any
+}
+
+func (r *reductionSort[T]) Len() int { return len(r.reduction) }
+
+func (r *reductionSort[T]) Swap(i, j int) {
+	r.reduction[i], r.reduction[j] = r.reduction[j], r.reduction[i]
+	r.values[i], r.values[j] = r.values[j], r.values[i]
+}
+
+func (r *reductionSort[T]) Less(i, j int) bool {
+	c, err := compare(r.values[i], r.values[j])
+	return err == nil && c < 0
+}
+
+// checkFieldType returns an error when T has a field named field of another type than V
+func checkFieldType[T, V any](field Field[T, V]) error {
+	var zero T
+	if tp, ok := fieldType(reflect.TypeOf(zero), string(field)); ok && tp != reflect.TypeFor[V]() {
+		return fmt.Errorf("field %s of %T is %s, not %s", field, zero, tp, reflect.TypeFor[V]())
+	}
+	return nil
+}
+
+// fieldType returns the type of the field of tp at the dotted path name
+func fieldType(tp reflect.Type, name string) (reflect.Type, bool) {
+	for _, part := range strings.Split(name, ".") {
+		for tp != nil && tp.Kind() == reflect.Ptr {
+			tp = tp.Elem()
+		}
+		if tp == nil || tp.Kind() != reflect.Struct {
+			return nil, false
+		}
+
+		field, ok := tp.FieldByName(part)
+		if !ok {
+			return nil, false
+		}
+		tp = field.Type
+	}
+	return tp, true
+}
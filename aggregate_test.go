@@ -7,6 +7,7 @@ package generichold_test
 import (
 	"fmt"
 	"github.com/rlshukhov/generichold"
+	"slices"
 	"testing"
 
 	"github.com/timshannon/badgerhold/v4"
@@ -551,3 +552,72 @@ func TestFindAggregateWithNoGroupBy(t *testing.T) {
 
 	})
 }
+
+func TestFindAggregateBy(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+
+		insertTestData(t, store)
+
+		result, err := generichold.FindAggregateBy(store, nil, itemCategory)
+		ok(t, err)
+		equals(t, 3, len(result))
+
+		expected := map[string]struct {
+			min, max int
+			count    uint64
+			sum      int
+			avg      float64
+		}{
+			"animal":  {min: 2, max: 14, count: 7, sum: 43, avg: 6.142857142857143},
+			"food":    {min: 7, max: 15, count: 5, sum: 46, avg: 9.2},
+			"vehicle": {min: 0, max: 11, count: 5, sum: 19, avg: 3.8},
+		}
+
+		for i := range result {
+			want, found := expected[result[i].Group()]
+			assert(t, found, "Unaccounted for grouping: %s", result[i].Group())
+
+			for _, item := range result[i].Reduction() {
+				equals(t, result[i].Group(), item.Category)
+			}
+
+			min, err := result[i].Min(itemID)
+			ok(t, err)
+			max, err := result[i].Max(itemID)
+			ok(t, err)
+			assert(t, min.equal(&testData[want.min]), "Expected %s min value of %v Got %v", result[i].Group(), testData[want.min], min)
+			assert(t, max.equal(&testData[want.max]), "Expected %s max value of %v Got %v", result[i].Group(), testData[want.max], max)
+			equals(t, want.count, result[i].Count())
+			sum, err := generichold.Sum(result[i], itemID)
+			ok(t, err)
+			equals(t, want.sum, sum)
+			avg, err := generichold.Avg(result[i], itemID)
+			ok(t, err)
+			equals(t, want.avg, avg)
+
+			ok(t, result[i].Sort(itemID))
+			reduction := result[i].Reduction()
+			assert(t, reduction[0].equal(&min), "Sort did not put the min value first")
+			assert(t, reduction[len(reduction)-1].equal(&max), "Sort did not put the max value last")
+		}
+
+		_, err = generichold.FindAggregateBy(store, nil, generichold.Field[ItemTest, int]("Category"))
+		assert(t, err != nil, "FindAggregateBy accepted a field of the wrong type")
+
+		_, err = generichold.Sum(result[0], generichold.Field[ItemTest, int64]("ID"))
+		assert(t, err != nil, "Sum accepted a field of the wrong type")
+		_, err = generichold.Avg(result[0], generichold.Field[ItemTest, int]("Missing"))
+		assert(t, err != nil, "Avg accepted a missing field")
+
+		// a misspelled field fails instead of panicking
+		missing := generichold.Field[ItemTest, int]("Missing")
+		_, err = result[0].Min(missing)
+		assert(t, err != nil, "Min accepted a missing field")
+		_, err = result[0].Max(missing)
+		assert(t, err != nil, "Max accepted a missing field")
+		before := slices.Clone(result[0].Reduction())
+		assert(t, result[0].Sort(missing) != nil, "Sort accepted a missing field")
+		equals(t, before, result[0].Reduction())
+	})
+}
@@ -0,0 +1,246 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// ReadOnlyStore reads the records of a store as they were at the badger commit timestamp ReadTs.
+// Queries are tested in memory.
+type ReadOnlyStore[T any] interface {
+	Count(query *Query[T]) (uint64, error)
+	Find(query *Query[T]) ([]T, error)
+	ForEach(query *Query[T], fn func(record *T) error) error
+	Get(key any) (T, error)
+	ReadTs() uint64
+}
+
+// SnapshotStore is a ReadOnlyStore holding a badger read transaction open, Discard releases it.
+type SnapshotStore[T any] interface {
+	ReadOnlyStore[T]
+	Discard()
+}
+
+type asOf[T any] struct {
+	store *store[T]
+	ts    uint64
+}
+
+// AsOf returns a view of the store as it was once the writes committed at or before ts were.
+// Results come in key order, the query's SortBy is ignored.
+// badgerhold opens badger unmanaged, so past versions are read from those badger keeps: set
+// badger.Options.NumVersionsToKeep to how many versions of a record should stay readable, the reads
+// of the view return ErrVersionsNotKept when it is 1. Even then badger keeps no more versions than that,
+// and compactions discard the older ones once no transaction reads them: a record whose version at ts
+// was discarded reads as if it did not exist. Snapshot keeps a state readable for as long as it is needed.
+func (s *store[T]) AsOf(ts uint64) ReadOnlyStore[T] {
+	return &asOf[T]{store: s, ts: ts}
+}
+
+type snapshot[T any] struct {
+	store *store[T]
+	// mu keeps Discard from ending txn under a read
+	mu        sync.RWMutex
+	txn       *badger.Txn
+	discarded bool
+}
+
+// Snapshot returns the view of the store at the last commit, which later writes and compactions leave
+// as they are: it reads through a badger read transaction, which keeps the versions it sees until Discard
+// is called. Queries are read the way FindWhere reads them. Its ReadTs can be passed to AsOf,
+// which reads the same state while badger keeps its versions.
+func (s *store[T]) Snapshot() SnapshotStore[T] {
+	return &snapshot[T]{store: s, txn: s.Badger().NewTransaction(false)}
+}
+
+func (v *snapshot[T]) ReadTs() uint64 {
+	return v.txn.ReadTs()
+}
+
+func (v *snapshot[T]) Count(query *Query[T]) (uint64, error) {
+	var count uint64
+	err := v.view(func(tx *badger.Txn) error {
+		var err error
+		count, err = v.store.TxCountKeys(tx, query)
+		return err
+	})
+	return count, err
+}
+
+func (v *snapshot[T]) Find(query *Query[T]) ([]T, error) {
+	var result []T
+	err := v.view(func(tx *badger.Txn) error {
+		var err error
+		result, err = v.store.TxFindWhere(tx, query)
+		return err
+	})
+	return result, err
+}
+
+// ForEach honors the Skip, Limit and SortBy of query
+func (v *snapshot[T]) ForEach(query *Query[T], fn func(record *T) error) error {
+	return v.view(func(tx *badger.Txn) error {
+		return v.store.forEachMatch(tx, query, func(_ []byte, record *T) error {
+			return fn(record)
+		})
+	})
+}
+
+func (v *snapshot[T]) Get(key any) (T, error) {
+	var result T
+	err := v.view(func(tx *badger.Txn) error {
+		var err error
+		result, err = v.store.TxGet(tx, key)
+		return err
+	})
+	return result, err
+}
+
+// Discard ends the transaction of the snapshot, its reads fail with badger.ErrDiscardedTxn from then on
+func (v *snapshot[T]) Discard() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.discarded {
+		v.txn.Discard()
+		v.discarded = true
+	}
+}
+
+// view runs fn in the transaction of the snapshot, unless it is discarded
+func (v *snapshot[T]) view(fn func(tx *badger.Txn) error) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.discarded {
+		return badger.ErrDiscardedTxn
+	}
+	return fn(v.txn)
+}
+
+func (a *asOf[T]) ReadTs() uint64 {
+	return a.ts
+}
+
+func (a *asOf[T]) Count(query *Query[T]) (uint64, error) {
+	var count uint64
+	err := a.ForEach(query, func(*T) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (a *asOf[T]) Find(query *Query[T]) ([]T, error) {
+	var result []T
+	err := a.ForEach(query, func(record *T) error {
+		result = append(result, *record)
+		return nil
+	})
+	return result, err
+}
+
+// ForEach honors the Skip and Limit of query
+func (a *asOf[T]) ForEach(query *Query[T], fn func(record *T) error) error {
+	skip, limit := 0, 0
+	if query != nil {
+		skip, limit = query.skip, query.limit
+	}
+
+	return a.view(func(tx *badger.Txn) error {
+		matches := 0
+		return a.scan(tx, a.store.prefix, func(key []byte, record *T) (bool, error) {
+			ok, err := query.matches(&row[T]{key: key, record: record, decode: a.store.options.decode})
+			if err != nil || !ok {
+				return err == nil, err
+			}
+
+			matches++
+			if matches <= skip {
+				return true, nil
+			}
+			err = fn(record)
+			return err == nil && (limit == 0 || matches < skip+limit), err
+		})
+	})
+}
+
+func (a *asOf[T]) Get(key any) (T, error) {
+	var result T
+	encoded, err := a.store.options.encode(key)
+	if err != nil {
+		return result, err
+	}
+
+	found := false
+	err = a.view(func(tx *badger.Txn) error {
+		key := append(a.store.prefix[:len(a.store.prefix):len(a.store.prefix)], encoded...)
+		return a.scan(tx, key, func(stored []byte, record *T) (bool, error) {
+			if bytes.Equal(stored, encoded) {
+				result, found = *record, true
+			}
+			return false, nil
+		})
+	})
+	if err == nil && !found {
+		err = badgerhold.ErrNotFound
+	}
+	return result, err
+}
+
+// view is the view of the store, failing when badger discards the past versions of keys
+func (a *asOf[T]) view(fn func(tx *badger.Txn) error) error {
+	if a.store.Badger().Opts().NumVersionsToKeep < 2 {
+		return ErrVersionsNotKept
+	}
+	return a.store.view(fn)
+}
+
+// scan decodes the records under prefix in key order at the version each had at a.ts,
+// leaving out the ones deleted by then
+func (a *asOf[T]) scan(tx *badger.Txn, prefix []byte, fn func(key []byte, record *T) (bool, error)) error {
+	options := badger.DefaultIteratorOptions
+	options.AllVersions = true
+	options.Prefix = prefix
+	iter := tx.NewIterator(options)
+	defer iter.Close()
+
+	var last []byte
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		if err := a.store.ctxErr(); err != nil {
+			return err
+		}
+
+		// the versions of a key come newest first, the first one at or before ts is the one
+		item := iter.Item()
+		if item.Version() > a.ts || (last != nil && bytes.Equal(item.Key(), last)) {
+			continue
+		}
+		last = item.KeyCopy(last[:0])
+		if item.IsDeletedOrExpired() {
+			continue
+		}
+
+		key := bytes.Clone(last[len(a.store.prefix):])
+		var record *T
+		err := item.Value(func(value []byte) error {
+			var err error
+			record, err = a.store.decodeRecord(key, value)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		next, err := fn(key, record)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
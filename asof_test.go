@@ -0,0 +1,84 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestAsOf(t *testing.T) {
+	opt := testOptions()
+	opt.NumVersionsToKeep = 10
+	testWrapWithOpt(t, opt, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+		ok(t, store.Insert(1, &KeyedItem{Name: "first"}))
+		ok(t, store.Insert(2, &KeyedItem{Name: "second"}))
+		snapshot := store.Snapshot()
+		defer snapshot.Discard()
+
+		ok(t, store.Update(1, &KeyedItem{Name: "updated"}))
+		ok(t, store.Delete(2))
+		ok(t, store.Insert(3, &KeyedItem{Name: "third"}))
+
+		record, err := snapshot.Get(uint64(1))
+		ok(t, err)
+		equals(t, "first", record.Name)
+		equals(t, uint64(1), record.ID)
+		record, err = snapshot.Get(uint64(2))
+		ok(t, err)
+		equals(t, "second", record.Name)
+		_, err = snapshot.Get(uint64(3))
+		equals(t, badgerhold.ErrNotFound, err)
+
+		name := generichold.Field[KeyedItem, string]("Name")
+		records, err := store.AsOf(snapshot.ReadTs()).Find(nil)
+		ok(t, err)
+		equals(t, []KeyedItem{{ID: 1, Name: "first"}, {ID: 2, Name: "second"}}, records)
+		count, err := snapshot.Count(generichold.Where(name.Eq("updated")))
+		ok(t, err)
+		equals(t, uint64(0), count)
+
+		now := store.Snapshot()
+		defer now.Discard()
+		records, err = now.Find(generichold.Where(name.Ne("")).Skip(1))
+		ok(t, err)
+		equals(t, []KeyedItem{{ID: 3, Name: "third"}}, records)
+		_, err = now.Get(uint64(2))
+		equals(t, badgerhold.ErrNotFound, err)
+	})
+}
+
+func TestAsOfNeedsVersions(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+		ok(t, store.Insert(1, &KeyedItem{Name: "first"}))
+		snapshot := store.Snapshot()
+
+		_, err := store.AsOf(snapshot.ReadTs()).Get(uint64(1))
+		equals(t, generichold.ErrVersionsNotKept, err)
+		_, err = store.AsOf(snapshot.ReadTs()).Count(nil)
+		equals(t, generichold.ErrVersionsNotKept, err)
+
+		// a snapshot holds its transaction open instead, so badger keeps the versions it reads
+		ok(t, store.Update(1, &KeyedItem{Name: "updated"}))
+		ok(t, store.Insert(2, &KeyedItem{Name: "second"}))
+		record, err := snapshot.Get(uint64(1))
+		ok(t, err)
+		equals(t, "first", record.Name)
+		records, err := snapshot.Find(nil)
+		ok(t, err)
+		equals(t, []KeyedItem{{ID: 1, Name: "first"}}, records)
+
+		snapshot.Discard()
+		_, err = snapshot.Get(uint64(1))
+		equals(t, badger.ErrDiscardedTxn, err)
+		_, err = snapshot.Count(nil)
+		equals(t, badger.ErrDiscardedTxn, err)
+	})
+}
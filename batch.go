@@ -0,0 +1,123 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// BatchError lists the records InsertMany or UpsertMany failed to write, every other record was written.
+type BatchError[K comparable] struct {
+	Errors map[K]error
+}
+
+func (e *BatchError[K]) Error() string {
+	return fmt.Sprintf("%d of the batch records were not written", len(e.Errors))
+}
+
+// Unwrap lets errors.Is and errors.As look into the record errors
+func (e *BatchError[K]) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// InsertMany inserts records in as few transactions as badger allows, starting a new transaction
+// whenever the current one would fail with badger.ErrTxnTooBig. Indexes are written along with
+// each record as Insert does. Records failing on their own, with badgerhold.ErrKeyExists for instance,
+// are reported in a *BatchError and left as they were: the transaction they failed in is discarded,
+// and the records before them are written again and committed before the batch goes on past them.
+// Any other error stops the batch with the earlier transactions committed.
+func (s *keyedStore[K, T]) InsertMany(records map[K]*T) error {
+	return s.writeMany(records, s.TxInsert)
+}
+
+// UpsertMany is InsertMany overwriting existing records, as Upsert does.
+func (s *keyedStore[K, T]) UpsertMany(records map[K]*T) error {
+	return s.writeMany(records, s.TxUpsert)
+}
+
+func (s *keyedStore[K, T]) writeMany(records map[K]*T, write func(tx *badger.Txn, key K, data *T) error) error {
+	keys := make([]K, 0, len(records))
+	saved := make(map[K]T, len(records))
+	for key, data := range records {
+		keys = append(keys, key)
+		saved[key] = *data
+	}
+	// restore puts records back the way they came, a discarded write may have changed them
+	restore := func(keys []K) {
+		for _, key := range keys {
+			*records[key] = saved[key]
+		}
+	}
+
+	failed := make(map[K]error)
+	chunk := len(keys)
+	// before is set when a record failed, the records before it are committed on their own next
+	before := 0
+	for len(keys) > 0 {
+		n := min(chunk, len(keys))
+		if before > 0 {
+			n, before = before, 0
+		}
+
+		failedAt := -1
+		var failure error
+		err := s.update(func(tx *badger.Txn) error {
+			restore(keys[:n])
+			for i, key := range keys[:n] {
+				err := write(tx, key, records[key])
+				if err == badger.ErrTxnTooBig {
+					// the records before it are written again on their own
+					chunk = i
+					return err
+				}
+				if err != nil {
+					failedAt, failure = i, err
+					return errRecordFailed
+				}
+			}
+			return nil
+		})
+
+		switch {
+		case err == errRecordFailed:
+			// the record may be half written, so the transaction is discarded. Running the whole of it again
+			// without the record would write the records before it once per failure, so only they are.
+			failed[keys[failedAt]] = failure
+			restore(keys[failedAt : failedAt+1])
+			keys = slices.Delete(keys, failedAt, failedAt+1)
+			before = failedAt
+			continue
+		case err == badger.ErrTxnTooBig:
+			if chunk == 0 {
+				// the record alone does not fit in a transaction
+				failed[keys[0]] = err
+				restore(keys[:1])
+				keys = keys[1:]
+				chunk = len(keys)
+			}
+			continue
+		case err != nil:
+			restore(keys)
+			return err
+		}
+		keys = keys[n:]
+	}
+
+	if len(failed) != 0 {
+		return &BatchError[K]{Errors: failed}
+	}
+	return nil
+}
+
+// errRecordFailed discards the transaction of a batch in which a record failed
+var errRecordFailed = errors.New("batch record failed")
@@ -0,0 +1,158 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"strings"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestInsertManyChunks(t *testing.T) {
+	opt := testOptions()
+	// a small memtable keeps badger transactions small too
+	opt.MemTableSize = 1 << 20
+	opt.ValueThreshold = 1 << 16
+	testWrapWithOpt(t, opt, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+
+		records := make(map[uint64]*KeyedItem)
+		for i := uint64(1); i <= 2000; i++ {
+			records[i] = &KeyedItem{Name: strings.Repeat("x", 1024)}
+		}
+		ok(t, store.InsertMany(records))
+
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(len(records)), count)
+
+		item, err := store.Get(1000)
+		ok(t, err)
+		equals(t, uint64(1000), item.ID)
+	})
+}
+
+func TestUpsertManyChunksRedone(t *testing.T) {
+	opt := testOptions()
+	opt.MemTableSize = 1 << 20
+	opt.ValueThreshold = 1 << 16
+	testWrapWithOpt(t, opt, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, VersionedItem](bh)
+
+		records := make(map[uint64]*VersionedItem)
+		for i := uint64(1); i <= 2000; i++ {
+			records[i] = &VersionedItem{Name: strings.Repeat("x", 1024)}
+		}
+		ok(t, store.InsertMany(records))
+
+		// the records of a transaction too big to commit are written again as they were read
+		for key := range records {
+			record, err := store.Get(key)
+			ok(t, err)
+			records[key] = &record
+		}
+		ok(t, store.UpsertMany(records))
+
+		count, err := store.Count(badgerhold.Where("Version").Eq(uint64(2)))
+		ok(t, err)
+		equals(t, uint64(len(records)), count)
+	})
+}
+
+func TestInsertManyFailures(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh)
+		ok(t, store.Insert(testData[0].Key, &testData[0]))
+
+		records := make(map[int]*ItemTest)
+		for i := range testData {
+			records[testData[i].Key] = &testData[i]
+		}
+
+		err := store.InsertMany(records)
+		var batchErr *generichold.BatchError[int]
+		assert(t, errors.As(err, &batchErr), "InsertMany did not return a BatchError")
+		equals(t, map[int]error{testData[0].Key: badgerhold.ErrKeyExists}, batchErr.Errors)
+		assert(t, errors.Is(err, badgerhold.ErrKeyExists), "BatchError does not unwrap to the record error")
+
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(len(testData)), count)
+
+		// the indexes are written along with the records
+		found, err := store.Find(badgerhold.Where("Category").Eq("vehicle").Index("Category"))
+		ok(t, err)
+		assert(t, len(found) > 1, "InsertMany records are missing from the index")
+
+		for key := range records {
+			records[key] = &ItemTest{Key: key, Name: "upserted"}
+		}
+		ok(t, store.UpsertMany(records))
+
+		count, err = store.Count(badgerhold.Where("Name").Eq("upserted"))
+		ok(t, err)
+		equals(t, uint64(len(testData)), count)
+	})
+}
+
+func TestInsertManyManyFailures(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		writes := 0
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh, generichold.WithValidator[KeyedItem](
+			generichold.ValidatorFunc[KeyedItem](func(*KeyedItem) error {
+				writes++
+				return nil
+			})))
+
+		records := make(map[uint64]*KeyedItem)
+		for i := uint64(1); i <= 4000; i++ {
+			records[i] = &KeyedItem{Name: "new"}
+			if i%2 == 0 {
+				ok(t, store.Insert(i, &KeyedItem{Name: "stored"}))
+			}
+		}
+		writes = 0
+
+		err := store.InsertMany(records)
+		var batchErr *generichold.BatchError[uint64]
+		assert(t, errors.As(err, &batchErr), "InsertMany did not return a BatchError: %v", err)
+		equals(t, 2000, len(batchErr.Errors))
+		// a failure writes the records before it once more, not every record of its transaction
+		assert(t, writes <= 3*len(records), "InsertMany wrote %d records for a batch of %d", writes, len(records))
+
+		count, err := store.Count(badgerhold.Where("Name").Eq("new"))
+		ok(t, err)
+		equals(t, uint64(2000), count)
+		count, err = store.Count(badgerhold.Where("Name").Eq("stored"))
+		ok(t, err)
+		equals(t, uint64(2000), count)
+	})
+}
+
+func TestInsertManyFailedRecordNotWritten(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, Account](bh)
+		ok(t, store.Insert(1, &Account{TenantID: 1, Email: "a@example.com", Login: "a", Phone: "1"}))
+
+		// badgerhold has written the record by the time its unique index fails
+		err := store.InsertMany(map[uint64]*Account{
+			2: {TenantID: 1, Email: "b@example.com", Login: "b", Phone: "1"},
+			3: {TenantID: 1, Email: "c@example.com", Login: "c", Phone: "3"},
+		})
+		var batchErr *generichold.BatchError[uint64]
+		assert(t, errors.As(err, &batchErr), "InsertMany did not return a BatchError: %v", err)
+		equals(t, map[uint64]error{2: badgerhold.ErrUniqueExists}, batchErr.Errors)
+
+		exists, err := store.Exists(2)
+		ok(t, err)
+		assert(t, !exists, "the failed record was written")
+		exists, err = store.Exists(3)
+		ok(t, err)
+		assert(t, exists, "the record after the failed one was not written")
+	})
+}
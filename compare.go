@@ -0,0 +1,74 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// compare orders values the same way badgerhold does for query criteria
+func compare(value, other any) (int, error) {
+	if value == nil || other == nil {
+		if value == other {
+			return 0, nil
+		}
+		return 0, &badgerhold.ErrTypeMismatch{Value: value, Other: other}
+	}
+
+	for reflect.TypeOf(value).Kind() == reflect.Ptr && !reflect.ValueOf(value).IsNil() {
+		value = reflect.ValueOf(value).Elem().Interface()
+	}
+	for reflect.TypeOf(other).Kind() == reflect.Ptr && !reflect.ValueOf(other).IsNil() {
+		other = reflect.ValueOf(other).Elem().Interface()
+	}
+
+	if t, ok := value.(time.Time); ok {
+		tother, ok := other.(time.Time)
+		if !ok {
+			return 0, &badgerhold.ErrTypeMismatch{Value: value, Other: other}
+		}
+		return t.Compare(tother), nil
+	}
+
+	if c, ok := value.(badgerhold.Comparer); ok {
+		return c.Compare(other)
+	}
+
+	v, o := reflect.ValueOf(value), reflect.ValueOf(other)
+	if v.Kind() != o.Kind() {
+		return 0, &badgerhold.ErrTypeMismatch{Value: value, Other: other}
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return order(v.Int() < o.Int(), v.Int() == o.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return order(v.Uint() < o.Uint(), v.Uint() == o.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return order(v.Float() < o.Float(), v.Float() == o.Float()), nil
+	case reflect.String:
+		return order(v.String() < o.String(), v.String() == o.String()), nil
+	case reflect.Bool:
+		return order(!v.Bool() && o.Bool(), v.Bool() == o.Bool()), nil
+	}
+
+	valS := fmt.Sprintf("%s", value)
+	otherS := fmt.Sprintf("%s", other)
+	return order(valS < otherS, valS == otherS), nil
+}
+
+func order(less, equal bool) int {
+	if equal {
+		return 0
+	}
+	if less {
+		return -1
+	}
+	return 1
+}
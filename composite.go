@@ -0,0 +1,611 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+var errMalformedEntry = errors.New("malformed composite index entry")
+
+// buildBatch is how many records a transaction building a composite index reads
+const buildBatch = 1000
+
+// WithCompositeIndex keeps an index of the records on the given top level fields of T, in that order,
+// e.g. WithCompositeIndex("Category", "Created"). Typed queries with Eq criteria on its leading fields
+// and no Or, optionally followed by Gt, Ge, Lt or Le criteria on the next field, seek to the first entry
+// in range and stop past the last one, reading a record only when the query tests fields the index lacks.
+// FindWhere, FindKeys, CountKeys, ForEachKey, Iter and FindPage read it, and return the matches in the order
+// of the index rather than in key order. Criteria values must have the type of their field.
+// The fields must be booleans, numbers, strings, byte slices or time.Time, and not badgerhold.Comparer.
+// UpdateMatching, DeleteMatching and inserts under badgerhold.NextSequence need the keys of the records
+// from their key field.
+// Open builds the index from the stored records the first time it is declared, and every store of T
+// writing to the same database must declare it from then on to keep it up to date.
+// Every method of the store of T returns an error when T lacks one of the fields.
+func WithCompositeIndex(fields ...string) Option {
+	return func(o *options) {
+		o.compositeIndexes = append(o.compositeIndexes, fields)
+	}
+}
+
+// compositeIndex is an index declared with WithCompositeIndex. An entry of a record is a badger key
+// of the values of the fields of the index encoded by appendSortable, followed by the record key.
+// The built key is set once every stored record has its entry.
+type compositeIndex struct {
+	prefix []byte
+	built  []byte
+	fields []reflect.StructField
+}
+
+func compositeIndexesOf[T any](o options, tp reflect.Type) ([]compositeIndex, error) {
+	var indexes []compositeIndex
+	for _, names := range o.compositeIndexes {
+		name := strings.Join(names, "+")
+		if len(names) == 0 || tp == nil || tp.Kind() != reflect.Struct {
+			return nil, errors.New("the composite index " + name + " needs the fields of a struct")
+		}
+
+		index := compositeIndex{
+			prefix: []byte("gh_index:" + typeName[T]() + ":" + name + ":"),
+			built:  []byte("gh_indexed:" + typeName[T]() + ":" + name),
+		}
+		for _, field := range names {
+			indexed, ok := tp.FieldByName(field)
+			if !ok || len(indexed.Index) != 1 {
+				return nil, errors.New("the composite index " + name + " of " + tp.Name() + " has no field " + field)
+			}
+			if !sortable(indexed.Type) {
+				return nil, errors.New("the composite index " + name + " of " + tp.Name() + " can't sort the field " +
+					field + " of type " + indexed.Type.String())
+			}
+			index.fields = append(index.fields, indexed)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// buildComposite writes the entries of the stored records to index, unless it is built already
+func (s *store[T]) buildComposite(index *compositeIndex) error {
+	return s.buildIndex(index.built, index.prefix, func(tx *badger.Txn, key []byte, record *T) error {
+		entry, err := s.compositeEntry(index, rawKey(key), record)
+		if err != nil {
+			return err
+		}
+		return tx.Set(entry, nil)
+	})
+}
+
+// buildIndex passes every stored record to add in transactions of buildBatch records, then sets built,
+// unless built is set already. Entries under prefix left by a build which did not finish are dropped first.
+func (s *store[T]) buildIndex(built, prefix []byte, add func(tx *badger.Txn, key []byte, record *T) error) error {
+	var done bool
+	err := s.view(func(tx *badger.Txn) error {
+		var err error
+		done, err = exists(tx, built)
+		return err
+	})
+	if err != nil || done {
+		return err
+	}
+
+	for done = false; !done; {
+		err := s.update(func(tx *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.PrefetchValues = false
+			opts.Prefix = prefix
+			iter := tx.NewIterator(opts)
+			defer iter.Close()
+
+			var entries [][]byte
+			for iter.Rewind(); iter.Valid() && len(entries) < buildBatch; iter.Next() {
+				entries = append(entries, iter.Item().KeyCopy(nil))
+			}
+			for _, entry := range entries {
+				if err := tx.Delete(entry); err != nil {
+					return err
+				}
+			}
+			done = len(entries) < buildBatch
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var after, last []byte
+	for done = false; !done; after = last {
+		err := s.update(func(tx *badger.Txn) error {
+			iter := tx.NewIterator(badger.DefaultIteratorOptions)
+			defer iter.Close()
+
+			start := append(s.prefix[:len(s.prefix):len(s.prefix)], after...)
+			if after != nil {
+				start = append(start, 0)
+			}
+			read := 0
+			for iter.Seek(start); iter.ValidForPrefix(s.prefix) && read < buildBatch; iter.Next() {
+				item := iter.Item()
+				key := item.KeyCopy(nil)[len(s.prefix):]
+				err := item.Value(func(value []byte) error {
+					record, err := s.decodeStored(key, value)
+					if err != nil {
+						return err
+					}
+					return add(tx, key, record)
+				})
+				if err != nil {
+					return err
+				}
+				last = key
+				read++
+			}
+
+			done = read < buildBatch
+			if done {
+				return tx.Set(built, nil)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exists reports whether badger holds key
+func exists(tx *badger.Txn, key []byte) (bool, error) {
+	_, err := tx.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// indexComposite writes the composite index entries of data once it is written under key,
+// replacing those of stored
+func (s *store[T]) indexComposite(tx *badger.Txn, key any, data, stored *T) error {
+	if len(s.compositeIndexes) == 0 {
+		return nil
+	}
+	if key == badgerhold.NextSequence() {
+		if s.keyIndex == nil {
+			return ErrNoKeyField
+		}
+		key = s.recordKey(data)
+	}
+
+	err := s.dropComposite(tx, key, stored)
+	if err != nil {
+		return err
+	}
+	for i := range s.compositeIndexes {
+		entry, err := s.compositeEntry(&s.compositeIndexes[i], key, data)
+		if err != nil {
+			return err
+		}
+		err = tx.Set(entry, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropComposite removes the composite index entries of stored, the record under key
+// deleted or about to be overwritten
+func (s *store[T]) dropComposite(tx *badger.Txn, key any, stored *T) error {
+	if len(s.compositeIndexes) == 0 || stored == nil {
+		return nil
+	}
+
+	for i := range s.compositeIndexes {
+		entry, err := s.compositeEntry(&s.compositeIndexes[i], key, stored)
+		if err != nil {
+			return err
+		}
+		err = tx.Delete(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropIndexes removes the unique and composite index entries of stored, the record under key
+func (s *store[T]) dropIndexes(tx *badger.Txn, key any, stored *T) error {
+	err := s.dropUnique(tx, stored)
+	if err == nil {
+		err = s.dropComposite(tx, key, stored)
+	}
+	return err
+}
+
+// indexed reports whether generichold keeps indexes of its own for T
+func (s *store[T]) indexed() bool {
+	return len(s.uniqueGroups) != 0 || len(s.compositeIndexes) != 0
+}
+
+func (s *store[T]) compositeEntry(index *compositeIndex, key any, record *T) ([]byte, error) {
+	entry := index.prefix[:len(index.prefix):len(index.prefix)]
+	for _, field := range index.fields {
+		entry = appendSortable(entry, reflect.ValueOf(record).Elem().FieldByIndex(field.Index))
+	}
+
+	encoded, err := s.encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return append(entry, encoded...), nil
+}
+
+// compositePlan is the range of entries of a composite index a query is read from
+type compositePlan struct {
+	index *compositeIndex
+	// prefix is the prefix of the index followed by the values of the Eq criteria
+	prefix []byte
+	// start is the first entry to read, stop the first one past the range, nil to read every entry under prefix
+	start, stop []byte
+}
+
+// compositeFor returns the plan of the built composite index with the most leading fields query has Eq criteria on,
+// ranking a range on the field after them above one more Eq criterion. It returns nil when there is none.
+func (s *store[T]) compositeFor(tx *badger.Txn, query *Query[T]) (*compositePlan, error) {
+	if query == nil || len(query.ors) != 0 {
+		return nil, nil
+	}
+
+	var best *compositePlan
+	bestScore := 0
+	for i := range s.compositeIndexes {
+		plan, score := query.compositePlan(&s.compositeIndexes[i])
+		if score <= bestScore {
+			continue
+		}
+		// an index declared by another store may lack records written before the store built it
+		built, err := exists(tx, plan.index.built)
+		if err != nil {
+			return nil, err
+		}
+		if built {
+			best, bestScore = plan, score
+		}
+	}
+	return best, nil
+}
+
+// compositePlan returns the range of the entries of index q can match, scored twice the number of its Eq
+// criteria on the leading fields plus one for a range on the next field, zero when q has neither
+func (q *Query[T]) compositePlan(index *compositeIndex) (*compositePlan, int) {
+	plan := &compositePlan{index: index, prefix: index.prefix[:len(index.prefix):len(index.prefix)]}
+	score := 0
+	for _, field := range index.fields {
+		value, ok := q.bound(field, opEq)
+		if !ok {
+			break
+		}
+		plan.prefix = appendSortable(plan.prefix, value)
+		score += 2
+	}
+	plan.start = plan.prefix
+	if score == 2*len(index.fields) {
+		return plan, score
+	}
+
+	field := index.fields[score/2]
+	for _, criterion := range q.criteria {
+		if !q.boundOf(criterion, field) {
+			continue
+		}
+
+		at := appendSortable(plan.prefix[:len(plan.prefix):len(plan.prefix)], reflect.ValueOf(criterion.value))
+		switch criterion.op {
+		case opGt, opGe:
+			if criterion.op == opGt {
+				at = successor(at)
+			}
+			if bytes.Compare(at, plan.start) > 0 {
+				plan.start = at
+			}
+		case opLt, opLe:
+			if criterion.op == opLe {
+				at = successor(at)
+			}
+			if plan.stop == nil || bytes.Compare(at, plan.stop) < 0 {
+				plan.stop = at
+			}
+		default:
+			continue
+		}
+		if score%2 == 0 {
+			score++
+		}
+	}
+	return plan, score
+}
+
+// bound returns the value of a criterion of q with op on field
+func (q *Query[T]) bound(field reflect.StructField, op compareOp) (reflect.Value, bool) {
+	for _, criterion := range q.criteria {
+		if criterion.op == op && q.boundOf(criterion, field) {
+			return reflect.ValueOf(criterion.value), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// boundOf reports whether criterion compares field to a value of its type, which encodes the way the field does.
+// A field named like badgerhold.Key is never one, criteria on it test the record key.
+func (q *Query[T]) boundOf(criterion Criterion[T], field reflect.StructField) bool {
+	return criterion.op != opNone && criterion.field == field.Name && field.Name != badgerhold.Key &&
+		reflect.TypeOf(criterion.value) == field.Type
+}
+
+// scanComposite tests query against the entries of plan in index order, starting after the entry after
+// when it is set, and passes fn the position of the matching entries with their keys. The records are left
+// empty but for the fields of the index, unless records is set or the query tests other fields.
+func (s *store[T]) scanComposite(tx *badger.Txn, plan *compositePlan, query *Query[T], after []byte, records bool,
+	fn func(position, key []byte, record *T) (bool, error)) error {
+	fields := make(map[string]struct{})
+	covered := !records && !query.fields(fields) && s.expiresIndex == nil
+	delete(fields, badgerhold.Key)
+	for _, field := range plan.index.fields {
+		delete(fields, field.Name)
+	}
+	covered = covered && len(fields) == 0
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = plan.prefix
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	start := plan.start
+	if len(after) != 0 {
+		resume := append(plan.index.prefix[:len(plan.index.prefix):len(plan.index.prefix)], after...)
+		// the first key past the entry itself
+		if resume = append(resume, 0); bytes.Compare(resume, start) > 0 {
+			start = resume
+		}
+	}
+
+	for iter.Seek(start); iter.ValidForPrefix(plan.prefix); iter.Next() {
+		if err := s.ctxErr(); err != nil {
+			return err
+		}
+
+		entry := iter.Item().KeyCopy(nil)
+		if plan.stop != nil && bytes.Compare(entry, plan.stop) >= 0 {
+			return nil
+		}
+
+		record := new(T)
+		key := entry[len(plan.index.prefix):]
+		for _, field := range plan.index.fields {
+			var err error
+			key, err = cutSortable(key, reflect.ValueOf(record).Elem().FieldByIndex(field.Index))
+			if err != nil {
+				return err
+			}
+		}
+
+		if !covered {
+			item, err := tx.Get(append(s.prefix[:len(s.prefix):len(s.prefix)], key...))
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err == nil {
+				err = item.Value(func(value []byte) error {
+					record, err = s.decodeRecord(key, value)
+					return err
+				})
+			}
+			if err != nil {
+				return err
+			}
+			if s.expired(record) {
+				continue
+			}
+		}
+
+		ok, err := query.matches(&row[T]{key: key, record: record, decode: s.options.decode})
+		if err != nil {
+			return err
+		}
+		if ok {
+			next, err := fn(entry[len(plan.index.prefix):], key, record)
+			if err != nil || !next {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// successor returns the first key after every key starting with prefix
+func successor(prefix []byte) []byte {
+	next := bytes.Clone(prefix)
+	for i := len(next) - 1; i >= 0; i-- {
+		if next[i] != 0xff {
+			next[i]++
+			return next[:i+1]
+		}
+	}
+	// the prefixes of the indexes start with "gh_", so they never get here
+	panic("no key follows " + string(prefix))
+}
+
+// sortable reports whether appendSortable can encode values of tp
+func sortable(tp reflect.Type) bool {
+	// a Comparer sorts its own way, which the bytes of its value don't follow
+	comparer := reflect.TypeOf((*badgerhold.Comparer)(nil)).Elem()
+	if tp.Implements(comparer) || reflect.PointerTo(tp).Implements(comparer) {
+		return false
+	}
+
+	switch tp.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return tp.Elem().Kind() == reflect.Uint8
+	}
+	return tp == timeType
+}
+
+// appendSortable appends value to b in bytes which sort the way compare orders values, and which end on their own,
+// so that the values of the following fields never change the order of the entries.
+// Strings and byte slices have their zero bytes escaped and end with 0x00 0x01.
+func appendSortable(b []byte, value reflect.Value) []byte {
+	if value.Type() == timeType {
+		t := value.Interface().(time.Time)
+		b = binary.BigEndian.AppendUint64(b, uint64(t.Unix())^1<<63)
+		return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.BigEndian.AppendUint64(b, uint64(value.Int())^1<<63)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.BigEndian.AppendUint64(b, value.Uint())
+	case reflect.Float32, reflect.Float64:
+		// -0 is equal to 0
+		bits := math.Float64bits(value.Float() + 0)
+		if bits&(1<<63) != 0 {
+			bits = ^bits
+		} else {
+			bits ^= 1 << 63
+		}
+		return binary.BigEndian.AppendUint64(b, bits)
+	case reflect.String:
+		return appendEscaped(b, []byte(value.String()))
+	default:
+		return appendEscaped(b, value.Bytes())
+	}
+}
+
+func appendEscaped(b, value []byte) []byte {
+	for _, c := range value {
+		b = append(b, c)
+		if c == 0 {
+			b = append(b, 0xff)
+		}
+	}
+	return append(b, 0, 1)
+}
+
+// cutSortable decodes the first value appended with appendSortable off b into value, returning the rest of b
+func cutSortable(b []byte, value reflect.Value) ([]byte, error) {
+	fixed := func(n int) ([]byte, error) {
+		if len(b) < n {
+			return nil, errMalformedEntry
+		}
+		return b[:n], nil
+	}
+
+	if value.Type() == timeType {
+		part, err := fixed(12)
+		if err != nil {
+			return nil, err
+		}
+		seconds := int64(binary.BigEndian.Uint64(part) ^ 1<<63)
+		value.Set(reflect.ValueOf(time.Unix(seconds, int64(binary.BigEndian.Uint32(part[8:])))))
+		return b[12:], nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		part, err := fixed(1)
+		if err != nil {
+			return nil, err
+		}
+		value.SetBool(part[0] == 1)
+		return b[1:], nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		part, err := fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		value.SetInt(int64(binary.BigEndian.Uint64(part) ^ 1<<63))
+		return b[8:], nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		part, err := fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		value.SetUint(binary.BigEndian.Uint64(part))
+		return b[8:], nil
+	case reflect.Float32, reflect.Float64:
+		part, err := fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		bits := binary.BigEndian.Uint64(part)
+		if bits&(1<<63) != 0 {
+			bits ^= 1 << 63
+		} else {
+			bits = ^bits
+		}
+		value.SetFloat(math.Float64frombits(bits))
+		return b[8:], nil
+	}
+
+	var unescaped []byte
+	for i := 0; i+1 < len(b); i++ {
+		if b[i] != 0 {
+			unescaped = append(unescaped, b[i])
+			continue
+		}
+		switch b[i+1] {
+		case 0xff:
+			unescaped = append(unescaped, 0)
+			i++
+		case 1:
+			if value.Kind() == reflect.String {
+				value.SetString(string(unescaped))
+			} else {
+				value.SetBytes(unescaped)
+			}
+			return b[i+2:], nil
+		default:
+			return nil, errMalformedEntry
+		}
+	}
+	return nil, errMalformedEntry
+}
+
+// appendPart appends value to b after its length, so that values never run into each other
+func appendPart(b, value []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(value)))
+	return append(b, value...)
+}
+
+// cutPart splits the first value appended with appendPart off b
+func cutPart(b []byte) (value, rest []byte, err error) {
+	if len(b) < 4 || uint64(len(b)-4) < uint64(binary.BigEndian.Uint32(b)) {
+		return nil, nil, errMalformedEntry
+	}
+	n := 4 + int(binary.BigEndian.Uint32(b))
+	return b[4:n], b[n:], nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"github.com/rlshukhov/generichold"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type Listing struct {
+	ID       uint64 `badgerhold:"key"`
+	Category string
+	Price    int
+	Title    string
+}
+
+var (
+	listingCategory = generichold.Field[Listing, string]("Category")
+	listingPrice    = generichold.Field[Listing, int]("Price")
+	listingTitle    = generichold.Field[Listing, string]("Title")
+)
+
+func TestCompositeIndexFindKeys(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh, generichold.WithCompositeIndex("Category", "ID"))
+		for i := range testData {
+			ok(t, store.Insert(testData[i].Key, &testData[i]))
+		}
+
+		// the index must not change what a query matches
+		for _, tst := range typedTestResults {
+			t.Run(tst.name, func(t *testing.T) {
+				keys, err := store.FindKeys(tst.query)
+				ok(t, err)
+
+				expected := make([]int, 0, len(tst.result))
+				for _, i := range tst.result {
+					expected = append(expected, testData[i].Key)
+				}
+				slices.Sort(keys)
+				equals(t, expected, keys)
+			})
+		}
+
+		query := generichold.Where(itemCategory.Eq("vehicle")).And(itemID.Ge(10))
+		keys, err := store.FindKeys(query)
+		ok(t, err)
+		var expected []int
+		for _, item := range testData {
+			if item.Category == "vehicle" && item.ID >= 10 {
+				expected = append(expected, item.Key)
+			}
+		}
+		equals(t, expected, keys)
+
+		count, err := store.CountKeys(query.Limit(1))
+		ok(t, err)
+		equals(t, uint64(1), count)
+	})
+}
+
+func TestCompositeIndexMaintained(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, Listing](bh, generichold.WithCompositeIndex("Category", "Price"))
+		listings := []Listing{
+			{Category: "car", Price: 300, Title: "sedan"},
+			{Category: "car", Price: 100, Title: "hatchback"},
+			{Category: "bike", Price: 200, Title: "road"},
+			{Category: "car", Price: 500, Title: "coupe"},
+			{Category: "bike", Price: 50, Title: "bmx"},
+		}
+		for i := range listings {
+			_, err := store.InsertNext(&listings[i])
+			ok(t, err)
+		}
+
+		findKeys := func(query *generichold.Query[Listing]) []uint64 {
+			t.Helper()
+			keys, err := store.FindKeys(query)
+			ok(t, err)
+			return keys
+		}
+
+		// keys come in the order of the index, by price
+		cars := generichold.Where(listingCategory.Eq("car")).And(listingPrice.Ge(200))
+		equals(t, []uint64{listings[0].ID, listings[3].ID}, findKeys(cars))
+		// a field the index lacks is read from the records
+		equals(t, []uint64{listings[3].ID},
+			findKeys(generichold.Where(listingCategory.Eq("car")).And(listingTitle.Eq("coupe"))))
+
+		ok(t, store.Update(listings[1].ID, &Listing{Category: "car", Price: 250, Title: "hatchback"}))
+		ok(t, store.Update(listings[3].ID, &Listing{Category: "bike", Price: 500, Title: "coupe"}))
+		equals(t, []uint64{listings[1].ID, listings[0].ID}, findKeys(cars))
+
+		ok(t, store.Delete(listings[0].ID))
+		equals(t, []uint64{listings[1].ID}, findKeys(cars))
+
+		ok(t, store.UpdateMatching(badgerhold.Where("Category").Eq("bike"), func(record *Listing) error {
+			record.Category = "car"
+			return nil
+		}))
+		equals(t, []uint64{listings[2].ID, listings[1].ID, listings[3].ID}, findKeys(cars))
+
+		ok(t, store.DeleteMatching(badgerhold.Where("Price").Ge(300)))
+		equals(t, []uint64{listings[2].ID, listings[1].ID}, findKeys(cars))
+
+		// the index holds an entry for every record left, and none for the ones gone
+		var entries int
+		ok(t, bh.Badger().View(func(tx *badger.Txn) error {
+			iter := tx.NewIterator(badger.IteratorOptions{Prefix: []byte("gh_index:Listing:Category+Price:")})
+			defer iter.Close()
+			for iter.Rewind(); iter.Valid(); iter.Next() {
+				entries++
+			}
+			return nil
+		}))
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, int(count), entries)
+	})
+}
+
+func TestCompositeIndexRange(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, Listing](bh, generichold.WithCompositeIndex("Category", "Price"))
+		var listings []Listing
+		for i, price := range []int{-300, 250, 0, -5, 1000, 40, 250, -1, 7} {
+			for _, category := range []string{"car", "car\x00", "ca"} {
+				listing := Listing{Category: category, Price: price, Title: strconv.Itoa(i)}
+				_, err := store.InsertNext(&listing)
+				ok(t, err)
+				listings = append(listings, listing)
+			}
+		}
+
+		// the index only reads the entries in range, which the records are read for
+		read := 0
+		title := listingTitle.Match(func(string, *Listing) (bool, error) {
+			read++
+			return true, nil
+		})
+		query := generichold.Where(title).And(listingCategory.Eq("car")).And(listingPrice.Gt(-5)).
+			And(listingPrice.Le(250)).And(listingPrice.Lt(1000))
+		found, err := store.FindWhere(query)
+		ok(t, err)
+
+		var expected []Listing
+		for _, listing := range listings {
+			if listing.Category == "car" && listing.Price > -5 && listing.Price <= 250 {
+				expected = append(expected, listing)
+			}
+		}
+		slices.SortStableFunc(expected, func(a, b Listing) int { return a.Price - b.Price })
+		equals(t, expected, found)
+		equals(t, len(expected), read)
+
+		count, err := store.CountKeys(query.Skip(1).Limit(3))
+		ok(t, err)
+		equals(t, uint64(3), count)
+		found, err = store.FindWhere(generichold.Where(listingPrice.Lt(0)).And(listingCategory.Eq("car")).Limit(2))
+		ok(t, err)
+		equals(t, []Listing{listings[0], listings[3*3]}, found)
+
+		// pages follow the index too
+		var paged []Listing
+		cursor := ""
+		for {
+			page, err := store.FindPage(generichold.Where(listingCategory.Eq("car")).And(listingPrice.Gt(-5)).
+				And(listingPrice.Le(250)), cursor, 2)
+			ok(t, err)
+			paged = append(paged, page.Items...)
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+		equals(t, expected, paged)
+	})
+}
+
+func TestCompositeIndexBuilt(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		plain := generichold.OpenKeyed[uint64, Listing](bh)
+		for i := 0; i < 2500; i++ {
+			_, err := plain.InsertNext(&Listing{Category: []string{"car", "bike"}[i%2], Price: i})
+			ok(t, err)
+		}
+
+		// the records stored before the index was declared are indexed by Open
+		store := generichold.OpenKeyed[uint64, Listing](bh, generichold.WithCompositeIndex("Category", "Price"))
+		query := generichold.Where(listingCategory.Eq("bike")).And(listingPrice.Ge(2000))
+		count, err := store.CountKeys(query)
+		ok(t, err)
+		equals(t, uint64(250), count)
+
+		var entries int
+		ok(t, bh.Badger().View(func(tx *badger.Txn) error {
+			iter := tx.NewIterator(badger.IteratorOptions{Prefix: []byte("gh_index:Listing:Category+Price:")})
+			defer iter.Close()
+			for iter.Rewind(); iter.Valid(); iter.Next() {
+				entries++
+			}
+			return nil
+		}))
+		equals(t, 2500, entries)
+
+		ok(t, store.Delete(uint64(2001)))
+		count, err = generichold.OpenKeyed[uint64, Listing](bh, generichold.WithCompositeIndex("Category", "Price")).
+			CountKeys(query)
+		ok(t, err)
+		equals(t, uint64(249), count)
+	})
+}
+
+func TestCompositeIndexNeedsFields(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[Listing](bh, generichold.WithCompositeIndex("Category", "Missing"))
+		assert(t, store.Insert(1, &Listing{ID: 1}) != nil, "a composite index of a missing field was accepted")
+		_, err := store.Find(nil)
+		assert(t, err != nil, "Find ignored a composite index of a missing field")
+
+		_, err = generichold.Open[ItemTest](bh, generichold.WithCompositeIndex("Category", "Tags")).FindWhere(nil)
+		assert(t, err != nil, "a composite index of a field it can't sort was accepted")
+	})
+}
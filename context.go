@@ -0,0 +1,84 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"context"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// WithContext returns a view of the store whose methods fail with ctx.Err() once ctx is done.
+// Find, ForEach, Count, UpdateMatching and the other methods running a query check ctx between records,
+// DeleteMatching and FindAggregate check it before and after badgerhold runs them. Writes outside
+// of a caller's transaction check ctx again before committing, so a cancelled write leaves nothing behind.
+func (s *store[T]) WithContext(ctx context.Context) Store[T] {
+	return s.withContext(ctx)
+}
+
+func (s *keyedStore[K, T]) WithContext(ctx context.Context) KeyedStore[K, T] {
+	return &keyedStore[K, T]{store: s.store.withContext(ctx)}
+}
+
+func (s *store[T]) withContext(ctx context.Context) *store[T] {
+	result := *s
+	result.ctx = ctx
+	return &result
+}
+
+// ctxErr returns the error of Open, or ctx.Err()
+func (s *store[T]) ctxErr() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.ctx == nil {
+		return nil
+	}
+	return s.ctx.Err()
+}
+
+// checked reports whether the methods must check ctxErr and leave out expired records,
+// rather than leave the call to badgerhold
+func (s *store[T]) checked() bool {
+	return s.ctx != nil || s.err != nil || s.expiresIndex != nil
+}
+
+// view runs fn in a read-only transaction unless ctx is already done
+func (s *store[T]) view(fn func(tx *badger.Txn) error) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+	return s.Badger().View(fn)
+}
+
+// update runs fn in a read-write transaction retried on conflict, which is discarded when ctx is done before it commits
+func (s *store[T]) update(fn func(tx *badger.Txn) error) error {
+	return s.options.retry(func() error {
+		if err := s.ctxErr(); err != nil {
+			return err
+		}
+
+		return s.Badger().Update(func(tx *badger.Txn) error {
+			if err := fn(tx); err != nil {
+				return err
+			}
+			return s.ctxErr()
+		})
+	})
+}
+
+// write is update for a write of data, which the write changes in place: data is put back the way
+// it came before fn runs again on conflict, and when the transaction is not committed
+func (s *store[T]) write(data *T, fn func(tx *badger.Txn) error) error {
+	saved := *data
+	err := s.update(func(tx *badger.Txn) error {
+		*data = saved
+		return fn(tx)
+	})
+	if err != nil {
+		*data = saved
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"context"
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestWithContextResults(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+		insertTestData(t, store)
+		ctxStore := store.WithContext(context.Background())
+
+		for _, tst := range testResults {
+			t.Run(tst.name, func(t *testing.T) {
+				result, err := ctxStore.Find(tst.query)
+				ok(t, err)
+				equals(t, len(tst.result), len(result))
+
+				count, err := ctxStore.Count(tst.query)
+				ok(t, err)
+				equals(t, uint64(len(tst.result)), count)
+
+				one, err := ctxStore.FindOne(tst.query)
+				if len(tst.result) == 0 {
+					equals(t, badgerhold.ErrNotFound, err)
+					return
+				}
+				ok(t, err)
+				equals(t, result[0], one)
+			})
+		}
+	})
+}
+
+func TestWithContextCancelled(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh)
+		ok(t, store.Insert(testData[0].Key, &testData[0]))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ctxStore := store.WithContext(ctx)
+
+		_, err := ctxStore.Find(nil)
+		equals(t, context.Canceled, err)
+		_, err = ctxStore.Count(nil)
+		equals(t, context.Canceled, err)
+		_, err = ctxStore.Get(testData[0].Key)
+		equals(t, context.Canceled, err)
+		_, err = ctxStore.FindPage(nil, "", 10)
+		equals(t, context.Canceled, err)
+		equals(t, context.Canceled, ctxStore.Insert(testData[1].Key, &testData[1]))
+		equals(t, context.Canceled, ctxStore.Delete(testData[0].Key))
+		equals(t, context.Canceled, ctxStore.DeleteMatching(nil))
+		equals(t, context.Canceled, ctxStore.View(func(tx generichold.Tx[ItemTest]) error {
+			t.Fatal("View ran with a cancelled context")
+			return nil
+		}))
+
+		for range ctxStore.Iter(nil).Values() {
+			t.Fatal("Iter yielded with a cancelled context")
+		}
+
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(1), count)
+	})
+}
+
+func TestWithContextCancelBetweenRecords(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+		insertTestData(t, store)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctxStore := store.WithContext(ctx)
+
+		visited := 0
+		err := ctxStore.ForEach(nil, func(record *ItemTest) error {
+			visited++
+			if visited == 3 {
+				cancel()
+			}
+			return nil
+		})
+		equals(t, context.Canceled, err)
+		equals(t, 3, visited)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		ctxStore = store.WithContext(ctx)
+
+		updated := 0
+		err = ctxStore.UpdateMatching(nil, func(record *ItemTest) error {
+			updated++
+			record.Name = "cancelled"
+			cancel()
+			return nil
+		})
+		equals(t, context.Canceled, err)
+		equals(t, 1, updated)
+
+		// the cancelled update was discarded
+		count, err := store.Count(badgerhold.Where("Name").Eq("cancelled"))
+		ok(t, err)
+		equals(t, uint64(0), count)
+	})
+}
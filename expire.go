@@ -0,0 +1,163 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// ErrNoExpiresAtField is returned by InsertWithTTL when T has no field to keep the expiry in
+var ErrNoExpiresAtField = errors.New("type has no generichold expiresAt field")
+
+// sweepBatch is how many expired records SweepExpired deletes per transaction
+const sweepBatch = 1000
+
+// WithOnExpire sets fn to be called with every record SweepExpired deleted, once its deletion is committed.
+// Every method of the store of T returns an error when fn takes a different type.
+func WithOnExpire[T any](fn func(record *T)) Option {
+	return func(o *options) {
+		o.onExpire = fn
+	}
+}
+
+func onExpireOf[T any](o options) (func(record *T), error) {
+	if o.onExpire == nil {
+		return nil, nil
+	}
+
+	fn, ok := o.onExpire.(func(record *T))
+	if !ok {
+		var zero T
+		return nil, fmt.Errorf("OnExpire %T does not take %T", o.onExpire, zero)
+	}
+	return fn, nil
+}
+
+// InsertWithTTL inserts data to expire after ttl, setting its time.Time field tagged `generichold:"expiresAt"`.
+// A record with a non-zero expiresAt is deleted by the first SweepExpired after that time. Until then reads
+// leave it out, but for FindAggregate and the Skip and Limit of badgerhold queries, which count it; writes
+// still see it. badger's own TTL is not used, it would leave the badgerhold index entries of the record behind.
+func (s *store[T]) InsertWithTTL(key any, data *T, ttl time.Duration) error {
+	return s.withTTL(data, ttl, func() error {
+		return s.Insert(key, data)
+	})
+}
+
+func (s *store[T]) TxInsertWithTTL(tx *badger.Txn, key any, data *T, ttl time.Duration) error {
+	return s.withTTL(data, ttl, func() error {
+		return s.TxInsert(tx, key, data)
+	})
+}
+
+// withTTL sets the expiresAt field of data for the insert, putting it back when the insert fails
+func (s *store[T]) withTTL(data *T, ttl time.Duration, insert func() error) error {
+	if s.expiresIndex == nil {
+		return ErrNoExpiresAtField
+	}
+
+	field := reflect.ValueOf(data).Elem().FieldByIndex(s.expiresIndex)
+	saved := field.Interface()
+	field.Set(reflect.ValueOf(s.options.clock().Add(ttl)))
+
+	err := insert()
+	if err != nil {
+		field.Set(reflect.ValueOf(saved))
+	}
+	return err
+}
+
+// SweepExpired deletes the records whose expiresAt has passed with DeleteMatching, so badgerhold
+// removes their index entries, and hooks, soft delete and history apply. It commits every sweepBatch
+// records. Indexing the expiresAt field with a badgerhold struct tag saves reading every record.
+func (s *store[T]) SweepExpired() error {
+	if s.expiresIndex == nil {
+		return nil
+	}
+
+	now := s.options.clock()
+	for {
+		var expired []T
+		err := s.update(func(tx *badger.Txn) error {
+			expired = nil
+			err := s.forEach(tx, s.expiredQuery(now), func(record *T) error {
+				expired = append(expired, *record)
+				return nil
+			})
+			if err != nil || len(expired) == 0 {
+				return err
+			}
+			// the same query in the same transaction deletes the records just found
+			return s.TxDeleteMatching(tx, s.expiredQuery(now))
+		})
+		if err != nil {
+			return err
+		}
+
+		if s.onExpire != nil {
+			for i := range expired {
+				s.onExpire(&expired[i])
+			}
+		}
+		if len(expired) < sweepBatch {
+			return nil
+		}
+	}
+}
+
+// StartSweeper runs SweepExpired every interval until ctx is done, it must be done before the store is closed.
+// The errors of failed sweeps are sent on the returned channel, which is closed once the sweeper stops.
+// They are dropped while nobody receives them.
+func (s *store[T]) StartSweeper(ctx context.Context, interval time.Duration) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := s.SweepExpired(); err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		}
+	}()
+	return errs
+}
+
+// expired reports whether the expiresAt of record has passed
+func (s *store[T]) expired(record *T) bool {
+	if s.expiresIndex == nil {
+		return false
+	}
+	at := reflect.ValueOf(record).Elem().FieldByIndex(s.expiresIndex).Interface().(time.Time)
+	return !at.IsZero() && !at.After(s.options.clock())
+}
+
+// expiredQuery matches up to sweepBatch records with an expiresAt at or before now
+func (s *store[T]) expiredQuery(now time.Time) *badgerhold.Query {
+	tp := reflect.TypeOf(s.zeroValue())
+	name := tp.FieldByIndex(s.expiresIndex).Name
+
+	query := badgerhold.Where(name).Le(now).And(name).Gt(time.Time{})
+	if _, ok := indexField(tp, name); ok {
+		query = query.Index(name)
+	}
+	return query.Limit(sweepBatch)
+}
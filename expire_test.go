@@ -0,0 +1,114 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"context"
+	"github.com/rlshukhov/generichold"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type ExpiringItem struct {
+	ID      uint64 `badgerhold:"key"`
+	Name    string
+	Expires time.Time `generichold:"expiresAt" badgerholdIndex:"Expires"`
+}
+
+func TestSweepExpired(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		var lock sync.Mutex
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		var expired []string
+		store := generichold.OpenKeyed[uint64, ExpiringItem](bh,
+			generichold.WithClock(func() time.Time {
+				lock.Lock()
+				defer lock.Unlock()
+				return now
+			}),
+			generichold.WithOnExpire(func(record *ExpiringItem) {
+				lock.Lock()
+				defer lock.Unlock()
+				expired = append(expired, record.Name)
+			}))
+
+		item := ExpiringItem{Name: "minute"}
+		ok(t, store.InsertWithTTL(1, &item, time.Minute))
+		equals(t, now.Add(time.Minute), item.Expires)
+		ok(t, store.InsertWithTTL(2, &ExpiringItem{Name: "hour"}, time.Hour))
+		ok(t, store.Insert(3, &ExpiringItem{Name: "forever"}))
+
+		ok(t, store.SweepExpired())
+		equals(t, 0, len(expired))
+
+		now = now.Add(time.Minute)
+		ok(t, store.SweepExpired())
+		equals(t, []string{"minute"}, expired)
+		_, err := store.Get(1)
+		equals(t, badgerhold.ErrNotFound, err)
+
+		// the index entry went with the record
+		count, err := store.Count(badgerhold.Where("Expires").Le(now).Index("Expires"))
+		ok(t, err)
+		equals(t, uint64(1), count)
+
+		lock.Lock()
+		now = now.Add(time.Hour)
+		lock.Unlock()
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := store.StartSweeper(ctx, time.Millisecond)
+		deadline := time.Now().Add(5 * time.Second)
+		for swept := false; !swept && time.Now().Before(deadline); {
+			lock.Lock()
+			swept = len(expired) == 2
+			lock.Unlock()
+		}
+		cancel()
+		for err := range errs {
+			ok(t, err)
+		}
+		equals(t, []string{"minute", "hour"}, expired)
+
+		_, err = store.Get(3)
+		ok(t, err)
+		equals(t, generichold.ErrNoExpiresAtField, generichold.Open[KeyedItem](bh).InsertWithTTL(1, &KeyedItem{}, time.Hour))
+	})
+}
+
+func TestExpiredHiddenBeforeSweep(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := generichold.OpenKeyed[uint64, ExpiringItem](bh, generichold.WithClock(func() time.Time { return now }))
+		ok(t, store.InsertWithTTL(1, &ExpiringItem{Name: "minute"}, time.Minute))
+		ok(t, store.Insert(2, &ExpiringItem{Name: "forever"}))
+		now = now.Add(time.Minute)
+
+		_, err := store.Get(1)
+		equals(t, badgerhold.ErrNotFound, err)
+		exists, err := store.Exists(1)
+		ok(t, err)
+		assert(t, !exists, "Exists found an expired record")
+		records, err := store.Find(nil)
+		ok(t, err)
+		equals(t, []ExpiringItem{{ID: 2, Name: "forever"}}, records)
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(1), count)
+		keys, err := store.FindKeys(nil)
+		ok(t, err)
+		equals(t, []uint64{2}, keys)
+
+		// writes still see the record until it is swept
+		equals(t, badgerhold.ErrKeyExists, store.Insert(1, &ExpiringItem{Name: "again"}))
+		ok(t, store.SweepExpired())
+		ok(t, store.Insert(1, &ExpiringItem{Name: "again"}))
+		record, err := store.Get(1)
+		ok(t, err)
+		equals(t, "again", record.Name)
+	})
+}
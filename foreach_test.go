@@ -7,6 +7,7 @@ package generichold_test
 import (
 	"fmt"
 	"github.com/rlshukhov/generichold"
+	"slices"
 	"testing"
 	"time"
 
@@ -86,3 +87,65 @@ func TestIssue105ForEachKeys(t *testing.T) {
 		}))
 	})
 }
+
+func TestForEachKey(t *testing.T) {
+	type Person struct {
+		ID   uint64 `badgerhold:"key"`
+		Name string
+	}
+
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[Person](bh)
+
+		for _, name := range []string{"tester1", "tester2", "tester3"} {
+			ok(t, store.Insert(badgerhold.NextSequence(), &Person{Name: name}))
+		}
+
+		var keys []uint64
+		ok(t, generichold.ForEachKey(store, generichold.Where(generichold.Field[Person, string]("Name").Ne("tester2")), func(key uint64, record *Person) error {
+			equals(t, key, record.ID)
+			keys = append(keys, key)
+			return nil
+		}))
+		equals(t, []uint64{0, 2}, keys)
+
+		err := generichold.ForEachKey(store, nil, func(key int, record *Person) error {
+			return nil
+		})
+		assert(t, err != nil, "ForEachKey with a mismatched key type did not return an error")
+	})
+}
+
+func TestForEachKeyWithoutKeyField(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+
+		insertTestData(t, store)
+
+		// keys come from badger
+		var keys []int
+		ok(t, generichold.ForEachKey(store, generichold.Where(itemCategory.Eq("vehicle")), func(key int, record *ItemTest) error {
+			equals(t, "vehicle", record.Category)
+			keys = append(keys, key)
+			return nil
+		}))
+		var expected []int
+		for _, item := range testData {
+			if item.Category == "vehicle" {
+				expected = append(expected, item.Key)
+			}
+		}
+		slices.Sort(keys)
+		equals(t, expected, keys)
+
+		// or in the order of SortBy
+		var names []string
+		ok(t, generichold.ForEachKey(store, generichold.Where(itemCategory.Eq("animal")).SortBy("Name").Reverse().Limit(2),
+			func(key int, record *ItemTest) error {
+				equals(t, testData[key].Key, key)
+				names = append(names, record.Name)
+				return nil
+			}))
+		equals(t, []string{"zebra", "seal"}, names)
+	})
+}
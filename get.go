@@ -0,0 +1,75 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// GetMany reads the records of keys in a single read transaction, keys which are not found
+// are left out of the result.
+func (s *keyedStore[K, T]) GetMany(keys ...K) (map[K]T, error) {
+	var result map[K]T
+	err := s.view(func(tx *badger.Txn) error {
+		var err error
+		result, err = s.TxGetMany(tx, keys...)
+		return err
+	})
+	return result, err
+}
+
+func (s *keyedStore[K, T]) TxGetMany(tx *badger.Txn, keys ...K) (map[K]T, error) {
+	result := make(map[K]T, len(keys))
+	err := s.getEach(tx, keys, func(key K, record T, found bool) {
+		if found {
+			result[key] = record
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetList is GetMany keeping the order of keys, keys which are not found are returned in missing.
+func (s *keyedStore[K, T]) GetList(keys ...K) (records []T, missing []K, err error) {
+	err = s.view(func(tx *badger.Txn) error {
+		var err error
+		records, missing, err = s.TxGetList(tx, keys...)
+		return err
+	})
+	return records, missing, err
+}
+
+func (s *keyedStore[K, T]) TxGetList(tx *badger.Txn, keys ...K) (records []T, missing []K, err error) {
+	records = make([]T, 0, len(keys))
+	err = s.getEach(tx, keys, func(key K, record T, found bool) {
+		if found {
+			records = append(records, record)
+		} else {
+			missing = append(missing, key)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return records, missing, nil
+}
+
+func (s *keyedStore[K, T]) getEach(tx *badger.Txn, keys []K, fn func(key K, record T, found bool)) error {
+	for _, key := range keys {
+		record, err := s.store.TxGet(tx, key)
+		if err == badgerhold.ErrNotFound {
+			fn(key, record, false)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fn(key, record, true)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestGetMany(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh)
+		insertTestData(t, generichold.Open[ItemTest](bh))
+
+		result, err := store.GetMany(testData[2].Key, 1000, testData[0].Key)
+		ok(t, err)
+		equals(t, 2, len(result))
+		equals(t, testData[0].Name, result[testData[0].Key].Name)
+		equals(t, testData[2].Name, result[testData[2].Key].Name)
+
+		records, missing, err := store.GetList(testData[2].Key, 1000, testData[0].Key, 1001)
+		ok(t, err)
+		equals(t, []int{1000, 1001}, missing)
+		equals(t, 2, len(records))
+		equals(t, testData[2].Name, records[0].Name)
+		equals(t, testData[0].Name, records[1].Name)
+
+		records, missing, err = store.GetList()
+		ok(t, err)
+		equals(t, 0, len(records))
+		equals(t, 0, len(missing))
+	})
+}
@@ -1,6 +1,6 @@
 module github.com/rlshukhov/generichold
 
-go 1.21
+go 1.23
 
 require (
 	github.com/dgraph-io/badger/v4 v4.5.1
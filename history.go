@@ -0,0 +1,188 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Revision is a past state of a record, as it was before Actor replaced or deleted it at Time.
+// Revisions of a key are numbered from 1 in the order they were written.
+type Revision[T any] struct {
+	Number uint64
+	Time   time.Time
+	Actor  string
+	Record T
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx naming who writes through the stores bound to it with WithContext,
+// which the history of their records keeps.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set on ctx with WithActor, an empty string if there is none
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithHistory keeps the previous state of a record as a Revision on every Update, Upsert, UpdateMatching,
+// Delete and DeleteMatching, with the time and the actor of the store's context. UpdateMatching
+// and DeleteMatching need the keys of the records from their key field.
+func WithHistory() Option {
+	return func(o *options) {
+		o.history = true
+	}
+}
+
+// History returns the revisions kept for key, oldest first.
+func (s *store[T]) History(key any) ([]Revision[T], error) {
+	var result []Revision[T]
+	err := s.view(func(tx *badger.Txn) error {
+		var err error
+		result, err = s.TxHistory(tx, key)
+		return err
+	})
+	return result, err
+}
+
+func (s *store[T]) TxHistory(tx *badger.Txn, key any) ([]Revision[T], error) {
+	if err := s.ctxErr(); err != nil {
+		return nil, err
+	}
+
+	prefix, err := s.revisionPrefix(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := tx.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 10})
+	defer iter.Close()
+
+	var result []Revision[T]
+	for iter.Rewind(); iter.Valid(); iter.Next() {
+		var revision Revision[T]
+		err := iter.Item().Value(func(value []byte) error {
+			return s.options.decode(value, &revision)
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.loaded(&revision.Record)
+		result = append(result, revision)
+	}
+	return result, nil
+}
+
+// Revert writes the record of the given revision of key back, as an Update when a record is stored
+// under key and an Insert when there is none, so the replaced state is kept as a revision in turn.
+// It fails with badgerhold.ErrNotFound when there is no such revision.
+func (s *store[T]) Revert(key any, revision uint64) error {
+	return s.update(func(tx *badger.Txn) error {
+		return s.TxRevert(tx, key, revision)
+	})
+}
+
+func (s *store[T]) TxRevert(tx *badger.Txn, key any, revision uint64) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+
+	prefix, err := s.revisionPrefix(key)
+	if err != nil {
+		return err
+	}
+	item, err := tx.Get(binary.BigEndian.AppendUint64(prefix, revision))
+	if err == badger.ErrKeyNotFound {
+		return badgerhold.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var past Revision[T]
+	err = item.Value(func(value []byte) error {
+		return s.options.decode(value, &past)
+	})
+	if err != nil {
+		return err
+	}
+
+	current, err := s.get(tx, key)
+	if err == badgerhold.ErrNotFound {
+		return s.TxInsert(tx, key, &past.Record)
+	}
+	if err != nil {
+		return err
+	}
+	if s.versionIndex != nil {
+		// the past record replaces current, whatever version it had
+		s.version(&past.Record).SetUint(s.version(&current).Uint())
+	}
+	return s.TxUpdate(tx, key, &past.Record)
+}
+
+// keepRevision adds stored, the record under key before the write running, to its history.
+// It does nothing unless WithHistory is set, or when stored is nil.
+func (s *store[T]) keepRevision(tx *badger.Txn, key any, stored *T) error {
+	if !s.options.history || stored == nil {
+		return nil
+	}
+
+	prefix, err := s.revisionPrefix(key)
+	if err != nil {
+		return err
+	}
+
+	revision := Revision[T]{Number: 1, Time: s.options.clock(), Record: *stored}
+	if s.ctx != nil {
+		revision.Actor = ActorFromContext(s.ctx)
+	}
+
+	revision.Number = lastNumber(tx, prefix) + 1
+
+	value, err := s.options.encode(&revision)
+	if err != nil {
+		return err
+	}
+	return tx.Set(binary.BigEndian.AppendUint64(prefix, revision.Number), value)
+}
+
+// revisionPrefix returns the prefix of the revisions of key
+func (s *store[T]) revisionPrefix(key any) ([]byte, error) {
+	return s.keyPrefix(s.historyPrefix, key)
+}
+
+// keyPrefix returns prefix followed by the encoded key. The length of the encoded key goes first,
+// so that the entries of one key are not under the prefix of another.
+func (s *store[T]) keyPrefix(prefix []byte, key any) ([]byte, error) {
+	encoded, err := s.encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return appendPart(prefix[:len(prefix):len(prefix)], encoded), nil
+}
+
+// lastNumber returns the highest number of the entries under prefix, numbered by the big endian uint64
+// following it, 0 when there are none
+func lastNumber(tx *badger.Txn, prefix []byte) uint64 {
+	// the last entry is the first one iterating backwards from past the prefix
+	iter := tx.NewIterator(badger.IteratorOptions{Prefix: prefix, Reverse: true})
+	defer iter.Close()
+
+	iter.Seek(binary.BigEndian.AppendUint64(prefix, ^uint64(0)))
+	if !iter.Valid() {
+		return 0
+	}
+	return binary.BigEndian.Uint64(iter.Item().Key()[len(prefix):])
+}
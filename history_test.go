@@ -0,0 +1,76 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"context"
+	"github.com/rlshukhov/generichold"
+	"testing"
+	"time"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type AuditedItem struct {
+	ID      uint64 `badgerhold:"key"`
+	Name    string
+	Version uint64 `generichold:"version"`
+}
+
+func TestHistory(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := generichold.OpenKeyed[uint64, AuditedItem](bh,
+			generichold.WithHistory(), generichold.WithClock(func() time.Time { return now }))
+		alice := store.WithContext(generichold.WithActor(context.Background(), "alice"))
+
+		item := AuditedItem{Name: "first"}
+		ok(t, store.Insert(1, &item))
+		ok(t, store.Insert(10, &AuditedItem{Name: "other"}))
+
+		now = now.Add(time.Hour)
+		item.Name = "second"
+		ok(t, alice.Update(1, &item))
+		ok(t, store.UpdateMatching(badgerhold.Where("Name").Eq("second"), func(record *AuditedItem) error {
+			record.Name = "third"
+			return nil
+		}))
+
+		history, err := store.History(1)
+		ok(t, err)
+		equals(t, 2, len(history))
+		equals(t, uint64(1), history[0].Number)
+		equals(t, "first", history[0].Record.Name)
+		equals(t, "alice", history[0].Actor)
+		equals(t, now, history[0].Time.UTC())
+		equals(t, "second", history[1].Record.Name)
+		equals(t, "", history[1].Actor)
+
+		// the key 10 starts like the key 1, its history is apart
+		history, err = store.History(10)
+		ok(t, err)
+		equals(t, 0, len(history))
+
+		ok(t, alice.Revert(1, 1))
+		record, err := store.Get(1)
+		ok(t, err)
+		equals(t, "first", record.Name)
+		equals(t, uint64(4), record.Version)
+
+		ok(t, store.Delete(1))
+		history, err = store.History(1)
+		ok(t, err)
+		equals(t, 4, len(history))
+		equals(t, "first", history[3].Record.Name)
+
+		// a deleted record is inserted back
+		ok(t, store.Revert(1, 3))
+		record, err = store.Get(1)
+		ok(t, err)
+		equals(t, "third", record.Name)
+
+		equals(t, badgerhold.ErrNotFound, store.Revert(1, 99))
+	})
+}
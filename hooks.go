@@ -0,0 +1,95 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+// BeforeInserter is called on a record before Insert writes it, or Upsert writes it under a new key.
+// An error cancels the write. Hooks are implemented by T or *T, and run inside the transaction
+// of the write, so Tx methods run them in the caller's transaction.
+type BeforeInserter interface {
+	BeforeInsert() error
+}
+
+// AfterInserter is called on a record once Insert or an inserting Upsert wrote it,
+// inside the transaction of the write.
+type AfterInserter interface {
+	AfterInsert()
+}
+
+// BeforeUpdater is called on a record before Update, Upsert or UpdateMatching overwrite old with it.
+// An error cancels the write.
+type BeforeUpdater[T any] interface {
+	BeforeUpdate(old *T) error
+}
+
+// BeforeDeleter is called on a record before Delete or DeleteMatching remove it.
+// An error cancels the delete.
+type BeforeDeleter interface {
+	BeforeDelete() error
+}
+
+// AfterLoader is called on every record a store reads before it is returned or passed to a callback,
+// the results of badgerhold aggregates aside.
+type AfterLoader interface {
+	AfterLoad()
+}
+
+// hooks records which of the hook interfaces *T implements
+type hooks struct {
+	beforeInsert bool
+	afterInsert  bool
+	beforeUpdate bool
+	beforeDelete bool
+	afterLoad    bool
+}
+
+func hooksOf[T any]() hooks {
+	record := any(new(T))
+	_, beforeInsert := record.(BeforeInserter)
+	_, afterInsert := record.(AfterInserter)
+	_, beforeUpdate := record.(BeforeUpdater[T])
+	_, beforeDelete := record.(BeforeDeleter)
+	_, afterLoad := record.(AfterLoader)
+	return hooks{
+		beforeInsert: beforeInsert,
+		afterInsert:  afterInsert,
+		beforeUpdate: beforeUpdate,
+		beforeDelete: beforeDelete,
+		afterLoad:    afterLoad,
+	}
+}
+
+// loaded runs AfterLoad on a record read from the store
+func (s *store[T]) loaded(record *T) {
+	if s.hooks.afterLoad {
+		any(record).(AfterLoader).AfterLoad()
+	}
+}
+
+func (s *store[T]) beforeInsert(record *T) error {
+	if s.hooks.beforeInsert {
+		return any(record).(BeforeInserter).BeforeInsert()
+	}
+	return nil
+}
+
+func (s *store[T]) afterInsert(record *T) {
+	if s.hooks.afterInsert {
+		any(record).(AfterInserter).AfterInsert()
+	}
+}
+
+func (s *store[T]) beforeUpdate(record, old *T) error {
+	if s.hooks.beforeUpdate {
+		return any(record).(BeforeUpdater[T]).BeforeUpdate(old)
+	}
+	return nil
+}
+
+func (s *store[T]) beforeDelete(record *T) error {
+	if s.hooks.beforeDelete {
+		return any(record).(BeforeDeleter).BeforeDelete()
+	}
+	return nil
+}
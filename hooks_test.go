@@ -0,0 +1,114 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"strings"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+var (
+	hookEvents   []string
+	errEmptyName = errors.New("name is empty")
+	errLocked    = errors.New("record is locked")
+)
+
+type HookedItem struct {
+	ID     uint64 `badgerhold:"key"`
+	Name   string
+	Loaded bool
+}
+
+func (i *HookedItem) BeforeInsert() error {
+	i.Name = strings.TrimSpace(i.Name)
+	if i.Name == "" {
+		return errEmptyName
+	}
+	return nil
+}
+
+func (i *HookedItem) AfterInsert() {
+	hookEvents = append(hookEvents, "insert "+i.Name)
+}
+
+func (i *HookedItem) BeforeUpdate(old *HookedItem) error {
+	if old.Name == "locked" {
+		return errLocked
+	}
+	hookEvents = append(hookEvents, "update "+old.Name+" to "+i.Name)
+	return nil
+}
+
+func (i *HookedItem) BeforeDelete() error {
+	if i.Name == "locked" {
+		return errLocked
+	}
+	return nil
+}
+
+func (i *HookedItem) AfterLoad() {
+	i.Loaded = true
+}
+
+func TestHooks(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		hookEvents = nil
+		store := generichold.OpenKeyed[uint64, HookedItem](bh)
+
+		ok(t, store.Insert(1, &HookedItem{Name: " first "}))
+		ok(t, store.Insert(2, &HookedItem{Name: "locked"}))
+		equals(t, errEmptyName, store.Insert(3, &HookedItem{Name: " "}))
+		equals(t, []string{"insert first", "insert locked"}, hookEvents)
+
+		item, err := store.Get(1)
+		ok(t, err)
+		equals(t, "first", item.Name)
+		assert(t, item.Loaded, "AfterLoad was not called on Get")
+
+		items, err := store.Find(nil)
+		ok(t, err)
+		equals(t, 2, len(items))
+		for _, item := range items {
+			assert(t, item.Loaded, "AfterLoad was not called on Find")
+		}
+
+		hookEvents = nil
+		ok(t, store.Update(1, &HookedItem{Name: "second"}))
+		equals(t, errLocked, store.Update(2, &HookedItem{Name: "unlocked"}))
+		ok(t, store.Upsert(3, &HookedItem{Name: "third"}))
+		ok(t, store.Upsert(3, &HookedItem{Name: "fourth"}))
+		equals(t, []string{"update first to second", "insert third", "update third to fourth"}, hookEvents)
+
+		hookEvents = nil
+		err = store.UpdateMatching(badgerhold.Where("Name").Ne("locked"), func(record *HookedItem) error {
+			assert(t, record.Loaded, "AfterLoad was not called on UpdateMatching")
+			record.Name += "!"
+			return nil
+		})
+		ok(t, err)
+		equals(t, []string{"update second to second!", "update fourth to fourth!"}, hookEvents)
+
+		equals(t, errLocked, store.Delete(2))
+		equals(t, errLocked, store.DeleteMatching(nil))
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(3), count)
+		ok(t, store.Delete(1))
+
+		// hooks run inside the transaction, an error rolls back the writes before it
+		err = store.UpdateTx(func(tx generichold.Tx[HookedItem]) error {
+			ok(t, tx.Insert(4, &HookedItem{Name: "fifth"}))
+			return tx.Insert(5, &HookedItem{})
+		})
+		equals(t, errEmptyName, err)
+		exists, err := store.Exists(4)
+		ok(t, err)
+		assert(t, !exists, "the transaction was not rolled back")
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"errors"
+	"iter"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// errStopIteration stops a ForEach once the range loop body breaks
+var errStopIteration = errors.New("iteration stopped")
+
+// Iterator streams query results record by record in key order, or in the order of the SortBy of the query
+// once every match is read. Err reports the error which ended the iteration.
+type Iterator[K comparable, T any] struct {
+	store *keyedStore[K, T]
+	tx    *badger.Txn
+	query *Query[T]
+	err   error
+}
+
+func (s *keyedStore[K, T]) Iter(query *Query[T]) *Iterator[K, T] {
+	return &Iterator[K, T]{store: s, query: query}
+}
+
+func (s *keyedStore[K, T]) TxIter(tx *badger.Txn, query *Query[T]) *Iterator[K, T] {
+	return &Iterator[K, T]{store: s, tx: tx, query: query}
+}
+
+// All yields the key and record of every match: for k, v := range store.Iter(query).All()
+func (it *Iterator[K, T]) All() iter.Seq2[K, T] {
+	return func(yield func(K, T) bool) {
+		it.run(func(key K, record *T) bool {
+			return yield(key, *record)
+		})
+	}
+}
+
+// Keys yields the key of every match.
+func (it *Iterator[K, T]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		it.run(func(key K, _ *T) bool {
+			return yield(key)
+		})
+	}
+}
+
+// Values yields every matching record.
+func (it *Iterator[K, T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		it.err = it.view(func(tx *badger.Txn) error {
+			return it.store.forEachMatch(tx, it.query, func(_ []byte, record *T) error {
+				if !yield(*record) {
+					return errStopIteration
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func (it *Iterator[K, T]) Err() error {
+	return it.err
+}
+
+func (it *Iterator[K, T]) run(yield func(key K, record *T) bool) {
+	it.err = it.view(func(tx *badger.Txn) error {
+		return it.store.TxForEachKey(tx, it.query, func(key K, record *T) error {
+			if !yield(key, record) {
+				return errStopIteration
+			}
+			return nil
+		})
+	})
+}
+
+func (it *Iterator[K, T]) view(fn func(tx *badger.Txn) error) error {
+	var err error
+	if it.tx != nil {
+		err = fn(it.tx)
+	} else {
+		err = it.store.view(fn)
+	}
+
+	if err == errStopIteration {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func insertKeyedItems(t *testing.T, store generichold.KeyedStore[uint64, KeyedItem], names ...string) {
+	for _, name := range names {
+		_, err := store.InsertNext(&KeyedItem{Name: name})
+		ok(t, err)
+	}
+}
+
+func TestIterAll(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+		insertKeyedItems(t, store, "a", "b", "c", "d")
+
+		it := store.Iter(generichold.Where(generichold.Field[KeyedItem, string]("Name").Ne("b")))
+		var names []string
+		for key, record := range it.All() {
+			equals(t, key, record.ID)
+			names = append(names, record.Name)
+		}
+		ok(t, it.Err())
+		equals(t, []string{"a", "c", "d"}, names)
+	})
+}
+
+func TestIterBreak(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+		insertKeyedItems(t, store, "a", "b", "c", "d")
+
+		it := store.Iter(nil)
+		var keys []uint64
+		for key := range it.Keys() {
+			keys = append(keys, key)
+			if len(keys) == 2 {
+				break
+			}
+		}
+		ok(t, it.Err())
+		equals(t, []uint64{0, 1}, keys)
+	})
+}
+
+func TestTxIterValues(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh)
+		for i := range testData {
+			ok(t, store.Insert(testData[i].Key, &testData[i]))
+		}
+
+		ok(t, store.Badger().View(func(tx *badger.Txn) error {
+			it := store.TxIter(tx, generichold.Where(itemCategory.Eq("vehicle")))
+			count := 0
+			for record := range it.Values() {
+				equals(t, "vehicle", record.Category)
+				count++
+			}
+			equals(t, 5, count)
+			return it.Err()
+		}))
+
+		// keys are read from badger without a key field on the record type
+		it := store.Iter(generichold.Where(itemCategory.Eq("vehicle")))
+		for key, record := range it.All() {
+			equals(t, "vehicle", record.Category)
+			equals(t, testData[key].Key, key)
+		}
+		ok(t, it.Err())
+	})
+}
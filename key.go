@@ -0,0 +1,142 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// ErrNoKeyField is returned when a record key is requested but T has no badgerhold key field to carry it
+var ErrNoKeyField = errors.New("type has no badgerhold key field")
+
+// rawKey is a record key already encoded, as read from badger
+type rawKey []byte
+
+// encodeKey encodes a record key, leaving a rawKey as it is
+func (s *store[T]) encodeKey(key any) ([]byte, error) {
+	if raw, ok := key.(rawKey); ok {
+		return raw, nil
+	}
+	return s.options.encode(key)
+}
+
+// ForEachKey is ForEach with the record key passed along, fn is checked at compile time.
+// Keys are decoded from the badger keys as FindKeys does, so T needs no key field, but a key field
+// of T must be of type K. Records come in key order, or sorted by the SortBy of query once every
+// match is read.
+func ForEachKey[K, T any](s Store[T], query *Query[T], fn func(key K, record *T) error) error {
+	return s.View(func(tx Tx[T]) error {
+		return TxForEachKey(s, tx.Txn(), query, fn)
+	})
+}
+
+func TxForEachKey[K, T any](s Store[T], tx *badger.Txn, query *Query[T], fn func(key K, record *T) error) error {
+	field, err := keyFieldOf[K, T]()
+	if err != nil && err != ErrNoKeyField {
+		return err
+	}
+
+	if m, ok := s.(interface {
+		forEachMatch(tx *badger.Txn, query *Query[T], fn func(key []byte, record *T) error) error
+		decodeKey(encoded []byte, key any) error
+	}); ok {
+		return m.forEachMatch(tx, query, func(encoded []byte, record *T) error {
+			var key K
+			if err := m.decodeKey(encoded, &key); err != nil {
+				return err
+			}
+			return fn(key, record)
+		})
+	}
+
+	// other stores only pass keys in the key field
+	if err != nil {
+		return err
+	}
+	return s.TxForEach(tx, query.Build(), func(record *T) error {
+		return fn(field.get(record), record)
+	})
+}
+
+// recordKey accesses the key field of T, badgerhold fills it in on every read
+type recordKey[K, T any] struct {
+	index []int
+}
+
+func keyFieldOf[K, T any]() (recordKey[K, T], error) {
+	var zero T
+	tp := reflect.TypeOf(zero)
+	if tp == nil || tp.Kind() != reflect.Struct {
+		return recordKey[K, T]{}, ErrNoKeyField
+	}
+
+	field, ok := keyField(tp)
+	if !ok {
+		return recordKey[K, T]{}, ErrNoKeyField
+	}
+
+	var key K
+	if field.Type != reflect.TypeOf(key) {
+		return recordKey[K, T]{}, fmt.Errorf("key field %s of %s is %s, not %T", field.Name, tp.Name(), field.Type, key)
+	}
+
+	return recordKey[K, T]{index: field.Index}, nil
+}
+
+func (k recordKey[K, T]) get(record *T) K {
+	return reflect.ValueOf(record).Elem().FieldByIndex(k.index).Interface().(K)
+}
+
+func (k recordKey[K, T]) set(record *T, key K) {
+	reflect.ValueOf(record).Elem().FieldByIndex(k.index).Set(reflect.ValueOf(key))
+}
+
+// keyField mirrors badgerhold lookup of the key field
+func keyField(tp reflect.Type) (reflect.StructField, bool) {
+	for i := 0; i < tp.NumField(); i++ {
+		if strings.HasPrefix(string(tp.Field(i).Tag), badgerhold.BadgerholdKeyTag) {
+			return tp.Field(i), true
+		}
+
+		if tag := tp.Field(i).Tag.Get("badgerhold"); tag == "key" {
+			return tp.Field(i), true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// indexField mirrors badgerhold lookup of the indexes it builds from struct tags
+func indexField(tp reflect.Type, name string) (reflect.StructField, bool) {
+	field, ok := tp.FieldByName(name)
+	if !ok || len(field.Index) != 1 {
+		return field, false
+	}
+
+	if strings.Contains(string(field.Tag), badgerhold.BadgerHoldIndexTag) {
+		return field, field.Tag.Get(badgerhold.BadgerHoldIndexTag) != ""
+	}
+
+	tag := field.Tag.Get("badgerhold")
+	return field, tag == "index" || tag == "unique"
+}
+
+// taggedField finds the field of tp tagged `generichold:"<name>"`, alone or in a comma separated list
+func taggedField(tp reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < tp.NumField(); i++ {
+		if slices.Contains(strings.Split(tp.Field(i).Tag.Get("generichold"), ","), name) {
+			return tp.Field(i), true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,225 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// KeyedStore is Store[T] with the key type fixed to K, so mismatched keys are rejected at compile time.
+type KeyedStore[K comparable, T any] interface {
+	AsOf(ts uint64) ReadOnlyStore[T]
+	FindAggregate(query *badgerhold.Query, groupBy ...string) ([]*badgerhold.AggregateResult, error)
+	TxFindAggregate(tx *badger.Txn, query *badgerhold.Query, groupBy ...string) ([]*badgerhold.AggregateResult, error)
+	Delete(key K) error
+	DeleteMatching(query *badgerhold.Query) error
+	TxDelete(tx *badger.Txn, key K) error
+	TxDeleteMatching(tx *badger.Txn, query *badgerhold.Query) error
+	CompareAndSwap(key K, expected, data *T) (bool, error)
+	Count(query *badgerhold.Query) (uint64, error)
+	CountKeys(query *Query[T]) (uint64, error)
+	Exists(key K) (bool, error)
+	Find(query *badgerhold.Query) ([]T, error)
+	FindKeys(query *Query[T]) ([]K, error)
+	FindOne(query *badgerhold.Query) (T, error)
+	FindPage(query *Query[T], cursor string, pageSize int) (Page[T], error)
+	FindWhere(query *Query[T]) ([]T, error)
+	ForEach(query *badgerhold.Query, fn func(record *T) error) error
+	ForEachKey(query *Query[T], fn func(key K, record *T) error) error
+	Get(key K) (T, error)
+	GetList(keys ...K) (records []T, missing []K, err error)
+	GetMany(keys ...K) (map[K]T, error)
+	History(key K) ([]Revision[T], error)
+	TxCompareAndSwap(tx *badger.Txn, key K, expected, data *T) (bool, error)
+	TxCount(tx *badger.Txn, query *badgerhold.Query) (uint64, error)
+	TxCountKeys(tx *badger.Txn, query *Query[T]) (uint64, error)
+	TxExists(tx *badger.Txn, key K) (bool, error)
+	TxFind(tx *badger.Txn, query *badgerhold.Query) ([]T, error)
+	TxFindKeys(tx *badger.Txn, query *Query[T]) ([]K, error)
+	TxFindOne(tx *badger.Txn, query *badgerhold.Query) (T, error)
+	TxFindPage(tx *badger.Txn, query *Query[T], cursor string, pageSize int) (Page[T], error)
+	TxFindWhere(tx *badger.Txn, query *Query[T]) ([]T, error)
+	TxForEach(tx *badger.Txn, query *badgerhold.Query, fn func(record *T) error) error
+	TxForEachKey(tx *badger.Txn, query *Query[T], fn func(key K, record *T) error) error
+	TxGet(tx *badger.Txn, key K) (T, error)
+	TxGetList(tx *badger.Txn, keys ...K) (records []T, missing []K, err error)
+	TxGetMany(tx *badger.Txn, keys ...K) (map[K]T, error)
+	TxHistory(tx *badger.Txn, key K) ([]Revision[T], error)
+	Insert(key K, data *T) error
+	InsertMany(records map[K]*T) error
+	InsertNext(data *T) (K, error)
+	InsertWithTTL(key K, data *T, ttl time.Duration) error
+	Modify(key K, fn func(record *T) error) (T, error)
+	Purge(olderThan time.Duration) error
+	Revert(key K, revision uint64) error
+	Iter(query *Query[T]) *Iterator[K, T]
+	TxInsert(tx *badger.Txn, key K, data *T) error
+	TxInsertNext(tx *badger.Txn, data *T) (K, error)
+	TxInsertWithTTL(tx *badger.Txn, key K, data *T, ttl time.Duration) error
+	TxIter(tx *badger.Txn, query *Query[T]) *Iterator[K, T]
+	TxModify(tx *badger.Txn, key K, fn func(record *T) error) (T, error)
+	TxRevert(tx *badger.Txn, key K, revision uint64) error
+	TxUpdate(tx *badger.Txn, key K, data *T) error
+	TxUpdateMatching(tx *badger.Txn, query *badgerhold.Query, update func(record *T) error) error
+	TxUpsert(tx *badger.Txn, key K, data *T) error
+	Update(key K, data *T) error
+	UpdateMatching(query *badgerhold.Query, update func(record *T) error) error
+	Upsert(key K, data *T) error
+	UpsertMany(records map[K]*T) error
+	View(fn func(tx Tx[T]) error) error
+	UpdateTx(fn func(tx Tx[T]) error) error
+	Watch(ctx context.Context, query *Query[T]) (<-chan Change[T], error)
+	Bind(tx *Txn) Tx[T]
+	Snapshot() SnapshotStore[T]
+	StartSweeper(ctx context.Context, interval time.Duration) <-chan error
+	SweepExpired() error
+	WithContext(ctx context.Context) KeyedStore[K, T]
+	WithDeleted() DeletedView[T]
+	Badger() *badger.DB
+	Close() error
+}
+
+type keyedStore[K comparable, T any] struct {
+	*store[T]
+}
+
+func OpenKeyed[K comparable, T any](s *badgerhold.Store, opts ...Option) KeyedStore[K, T] {
+	return &keyedStore[K, T]{store: newStore[T](s, opts)}
+}
+
+func (s *keyedStore[K, T]) Delete(key K) error {
+	return s.store.Delete(key)
+}
+
+func (s *keyedStore[K, T]) TxDelete(tx *badger.Txn, key K) error {
+	return s.store.TxDelete(tx, key)
+}
+
+func (s *keyedStore[K, T]) CompareAndSwap(key K, expected, data *T) (bool, error) {
+	return s.store.CompareAndSwap(key, expected, data)
+}
+
+func (s *keyedStore[K, T]) TxCompareAndSwap(tx *badger.Txn, key K, expected, data *T) (bool, error) {
+	return s.store.TxCompareAndSwap(tx, key, expected, data)
+}
+
+func (s *keyedStore[K, T]) Exists(key K) (bool, error) {
+	return s.store.Exists(key)
+}
+
+func (s *keyedStore[K, T]) TxExists(tx *badger.Txn, key K) (bool, error) {
+	return s.store.TxExists(tx, key)
+}
+
+func (s *keyedStore[K, T]) ForEachKey(query *Query[T], fn func(key K, record *T) error) error {
+	return ForEachKey[K, T](s.store, query, fn)
+}
+
+func (s *keyedStore[K, T]) TxForEachKey(tx *badger.Txn, query *Query[T], fn func(key K, record *T) error) error {
+	return TxForEachKey[K, T](s.store, tx, query, fn)
+}
+
+func (s *keyedStore[K, T]) Get(key K) (T, error) {
+	return s.store.Get(key)
+}
+
+func (s *keyedStore[K, T]) TxGet(tx *badger.Txn, key K) (T, error) {
+	return s.store.TxGet(tx, key)
+}
+
+func (s *keyedStore[K, T]) Insert(key K, data *T) error {
+	return s.store.Insert(key, data)
+}
+
+// InsertNext inserts data under badgerhold.NextSequence() and returns the generated key.
+// T must have a uint64 key field, which badgerhold sets to the generated key.
+func (s *keyedStore[K, T]) InsertNext(data *T) (K, error) {
+	var key K
+	err := s.write(data, func(tx *badger.Txn) error {
+		var err error
+		key, err = s.TxInsertNext(tx, data)
+		return err
+	})
+	return key, err
+}
+
+func (s *keyedStore[K, T]) TxInsertNext(tx *badger.Txn, data *T) (K, error) {
+	var zero K
+	if reflect.TypeOf(zero) != reflect.TypeOf(uint64(0)) {
+		return zero, fmt.Errorf("sequence keys are uint64, not %T", zero)
+	}
+
+	field, err := keyFieldOf[K, T]()
+	if err != nil {
+		return zero, err
+	}
+
+	// badgerhold only sets the key field when it holds the zero value
+	field.set(data, zero)
+	err = s.store.TxInsert(tx, badgerhold.NextSequence(), data)
+	if err != nil {
+		return zero, err
+	}
+
+	return field.get(data), nil
+}
+
+func (s *keyedStore[K, T]) InsertWithTTL(key K, data *T, ttl time.Duration) error {
+	return s.store.InsertWithTTL(key, data, ttl)
+}
+
+func (s *keyedStore[K, T]) TxInsertWithTTL(tx *badger.Txn, key K, data *T, ttl time.Duration) error {
+	return s.store.TxInsertWithTTL(tx, key, data, ttl)
+}
+
+func (s *keyedStore[K, T]) Modify(key K, fn func(record *T) error) (T, error) {
+	return s.store.Modify(key, fn)
+}
+
+func (s *keyedStore[K, T]) TxModify(tx *badger.Txn, key K, fn func(record *T) error) (T, error) {
+	return s.store.TxModify(tx, key, fn)
+}
+
+func (s *keyedStore[K, T]) History(key K) ([]Revision[T], error) {
+	return s.store.History(key)
+}
+
+func (s *keyedStore[K, T]) TxHistory(tx *badger.Txn, key K) ([]Revision[T], error) {
+	return s.store.TxHistory(tx, key)
+}
+
+func (s *keyedStore[K, T]) Revert(key K, revision uint64) error {
+	return s.store.Revert(key, revision)
+}
+
+func (s *keyedStore[K, T]) TxRevert(tx *badger.Txn, key K, revision uint64) error {
+	return s.store.TxRevert(tx, key, revision)
+}
+
+func (s *keyedStore[K, T]) TxInsert(tx *badger.Txn, key K, data *T) error {
+	return s.store.TxInsert(tx, key, data)
+}
+
+func (s *keyedStore[K, T]) TxUpdate(tx *badger.Txn, key K, data *T) error {
+	return s.store.TxUpdate(tx, key, data)
+}
+
+func (s *keyedStore[K, T]) TxUpsert(tx *badger.Txn, key K, data *T) error {
+	return s.store.TxUpsert(tx, key, data)
+}
+
+func (s *keyedStore[K, T]) Update(key K, data *T) error {
+	return s.store.Update(key, data)
+}
+
+func (s *keyedStore[K, T]) Upsert(key K, data *T) error {
+	return s.store.Upsert(key, data)
+}
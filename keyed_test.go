@@ -0,0 +1,88 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"context"
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type KeyedItem struct {
+	ID   uint64 `badgerhold:"key"`
+	Name string
+}
+
+func TestKeyedStore(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh)
+
+		for i := range testData {
+			ok(t, store.Insert(testData[i].Key, &testData[i]))
+		}
+
+		result, err := store.Get(testData[3].Key)
+		ok(t, err)
+		assert(t, result.equal(&testData[3]), "Got %v wanted %v", result, testData[3])
+
+		ok(t, store.Delete(testData[3].Key))
+		_, err = store.Get(testData[3].Key)
+		equals(t, badgerhold.ErrNotFound, err)
+
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(len(testData)-1), count)
+	})
+}
+
+func TestKeyedStoreInsertNext(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+
+		for i, name := range []string{"first", "second", "third"} {
+			// a preset key field is replaced by the generated one
+			data := &KeyedItem{ID: 100, Name: name}
+			key, err := store.InsertNext(data)
+			ok(t, err)
+			equals(t, uint64(i), key)
+			equals(t, key, data.ID)
+		}
+
+		result, err := store.Get(1)
+		ok(t, err)
+		equals(t, "second", result.Name)
+
+		var keys []uint64
+		ok(t, store.ForEachKey(nil, func(key uint64, record *KeyedItem) error {
+			keys = append(keys, key)
+			return nil
+		}))
+		equals(t, []uint64{0, 1, 2}, keys)
+
+		// InsertNext runs in the store's transactions, which check its context
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = store.WithContext(ctx).InsertNext(&KeyedItem{Name: "cancelled"})
+		equals(t, context.Canceled, err)
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(3), count)
+	})
+}
+
+func TestKeyedStoreInsertNextWithoutKeyField(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, ItemTest](bh)
+
+		_, err := store.InsertNext(&ItemTest{Name: "test"})
+		equals(t, generichold.ErrNoKeyField, err)
+
+		intStore := generichold.OpenKeyed[int, KeyedItem](bh)
+		_, err = intStore.InsertNext(&KeyedItem{Name: "test"})
+		assert(t, err != nil, "InsertNext with a non uint64 key did not return an error")
+	})
+}
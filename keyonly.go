@@ -0,0 +1,256 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Exists reports whether a record is stored under key without reading it,
+// but for the expiry of a record, which is read from it.
+func (s *store[T]) Exists(key any) (bool, error) {
+	var exists bool
+	err := s.view(func(tx *badger.Txn) error {
+		var err error
+		exists, err = s.TxExists(tx, key)
+		return err
+	})
+	return exists, err
+}
+
+func (s *store[T]) TxExists(tx *badger.Txn, key any) (bool, error) {
+	if err := s.ctxErr(); err != nil {
+		return false, err
+	}
+
+	encoded, err := s.options.encode(key)
+	if err != nil {
+		return false, err
+	}
+	if s.expiresIndex != nil {
+		_, err = s.TxGet(tx, key)
+		if err == badgerhold.ErrNotFound {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	_, err = tx.Get(append(s.prefix[:len(s.prefix):len(s.prefix)], encoded...))
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CountKeys counts the records matching query, honoring its Skip and Limit. Records are not read
+// when every criterion is on the key, and only the index is read when they are on the key
+// and a single field indexed with a badgerhold struct tag. A query with Eq criteria on the leading
+// fields of a composite index, or a range on its first ones, seeks to them in that index instead.
+func (s *store[T]) CountKeys(query *Query[T]) (uint64, error) {
+	var count uint64
+	err := s.view(func(tx *badger.Txn) error {
+		var err error
+		count, err = s.TxCountKeys(tx, query)
+		return err
+	})
+	return count, err
+}
+
+func (s *store[T]) TxCountKeys(tx *badger.Txn, query *Query[T]) (uint64, error) {
+	var count uint64
+	err := s.matchingKeys(tx, query, func([]byte) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// FindKeys returns the keys of the records matching query in key order, or in the order of the
+// composite index the query is read from, reading as little as CountKeys does. T needs no key field, keys are decoded from badger keys.
+func (s *keyedStore[K, T]) FindKeys(query *Query[T]) ([]K, error) {
+	var keys []K
+	err := s.view(func(tx *badger.Txn) error {
+		var err error
+		keys, err = s.TxFindKeys(tx, query)
+		return err
+	})
+	return keys, err
+}
+
+func (s *keyedStore[K, T]) TxFindKeys(tx *badger.Txn, query *Query[T]) ([]K, error) {
+	var keys []K
+	err := s.matchingKeys(tx, query, func(encoded []byte) error {
+		var key K
+		err := s.options.decode(encoded, &key)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	return keys, err
+}
+
+// matchingKeys calls fn with the encoded key of every record matching query in key order,
+// or in the order of the composite index the query is read from
+func (s *store[T]) matchingKeys(tx *badger.Txn, query *Query[T], fn func(key []byte) error) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+
+	skip, limit, found := 0, 0, 0
+	if query != nil {
+		skip, limit = query.skip, query.limit
+	}
+	emit := func(key []byte) (bool, error) {
+		if skip > 0 {
+			skip--
+			return true, nil
+		}
+		if err := fn(key); err != nil {
+			return false, err
+		}
+		found++
+		return limit <= 0 || found < limit, nil
+	}
+
+	plan, err := s.compositeFor(tx, query)
+	if err != nil {
+		return err
+	}
+	if plan != nil {
+		return s.scanComposite(tx, plan, query, nil, false, func(_, key []byte, _ *T) (bool, error) {
+			return emit(key)
+		})
+	}
+
+	// the expiry of records is only read from them
+	fields := make(map[string]struct{})
+	if !query.fields(fields) && s.expiresIndex == nil {
+		delete(fields, badgerhold.Key)
+		if len(fields) == 0 {
+			return s.scanKeys(tx, func(key []byte) (bool, error) {
+				ok, err := query.matches(&row[T]{key: key, decode: s.options.decode})
+				if err != nil || !ok {
+					return err == nil, err
+				}
+				return emit(key)
+			})
+		}
+
+		if field, ok := s.indexField(fields); ok {
+			return s.scanIndex(tx, field, query, emit)
+		}
+	}
+
+	return s.scan(tx, nil, func(key []byte, record *T) (bool, error) {
+		ok, err := query.matches(&row[T]{key: key, record: record, decode: s.options.decode})
+		if err != nil || !ok {
+			return err == nil, err
+		}
+		return emit(key)
+	})
+}
+
+// indexField returns the field of T when fields is a single field badgerhold indexes from its struct tag
+func (s *store[T]) indexField(fields map[string]struct{}) (reflect.StructField, bool) {
+	var zero T
+	tp := reflect.TypeOf(zero)
+	if len(fields) != 1 || tp == nil || tp.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	// a Storer builds its own indexes, which can't be decoded back
+	if _, ok := any(&zero).(badgerhold.Storer); ok {
+		return reflect.StructField{}, false
+	}
+	if _, ok := any(zero).(badgerhold.Storer); ok {
+		return reflect.StructField{}, false
+	}
+
+	for name := range fields {
+		return indexField(tp, name)
+	}
+	return reflect.StructField{}, false
+}
+
+// scanKeys iterates the keys of T in key order without reading the records
+func (s *store[T]) scanKeys(tx *badger.Txn, fn func(key []byte) (bool, error)) error {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = s.prefix
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	for iter.Seek(s.prefix); iter.ValidForPrefix(s.prefix); iter.Next() {
+		if err := s.ctxErr(); err != nil {
+			return err
+		}
+
+		next, err := fn(iter.Item().KeyCopy(nil)[len(s.prefix):])
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanIndex tests query against the records of the index on field, which are left empty but for
+// the indexed value. The matching keys are passed to fn in key order.
+func (s *store[T]) scanIndex(tx *badger.Txn, field reflect.StructField, query *Query[T],
+	fn func(key []byte) (bool, error)) error {
+	prefix := []byte("_bhIndex:" + typeName[T]() + ":" + field.Name + ":")
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+	iter := tx.NewIterator(opts)
+	defer iter.Close()
+
+	var keys [][]byte
+	for iter.Seek(prefix); iter.ValidForPrefix(prefix); iter.Next() {
+		if err := s.ctxErr(); err != nil {
+			return err
+		}
+
+		item := iter.Item()
+		record := new(T)
+		value := reflect.ValueOf(record).Elem().FieldByIndex(field.Index)
+		err := s.options.decode(item.Key()[len(prefix):], value.Addr().Interface())
+		if err != nil {
+			return err
+		}
+
+		var list badgerhold.KeyList
+		err = item.Value(func(value []byte) error {
+			return s.options.decode(value, &list)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range list {
+			key = key[len(s.prefix):]
+			ok, err := query.matches(&row[T]{key: key, record: record, decode: s.options.decode})
+			if err != nil {
+				return err
+			}
+			if ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	slices.SortFunc(keys, bytes.Compare)
+	for _, key := range keys {
+		next, err := fn(key)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
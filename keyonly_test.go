@@ -0,0 +1,90 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"github.com/rlshukhov/generichold"
+	"slices"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestExists(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh)
+		ok(t, store.Insert(testData[0].Key, &testData[0]))
+
+		exists, err := store.Exists(testData[0].Key)
+		ok(t, err)
+		assert(t, exists, "inserted key does not exist")
+
+		exists, err = store.Exists(testData[1].Key)
+		ok(t, err)
+		assert(t, !exists, "missing key exists")
+	})
+}
+
+func TestFindKeys(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh)
+		insertTestData(t, generichold.Open[ItemTest](bh))
+
+		for _, tst := range typedTestResults {
+			t.Run(tst.name, func(t *testing.T) {
+				keys, err := store.FindKeys(tst.query)
+				ok(t, err)
+
+				expected := make([]int, 0, len(tst.result))
+				for _, i := range tst.result {
+					expected = append(expected, testData[i].Key)
+				}
+				slices.Sort(keys)
+				equals(t, expected, keys)
+
+				count, err := store.CountKeys(tst.query)
+				ok(t, err)
+				equals(t, uint64(len(tst.result)), count)
+			})
+		}
+
+		count, err := store.CountKeys(nil)
+		ok(t, err)
+		equals(t, uint64(len(testData)), count)
+
+		keys, err := store.FindKeys(generichold.Where(itemCategory.Eq("vehicle")).Skip(1).Limit(2))
+		ok(t, err)
+		equals(t, 2, len(keys))
+	})
+}
+
+func TestFindKeysSkipsDecoding(t *testing.T) {
+	decoded := 0
+	decode := func(data []byte, value any) error {
+		if _, ok := value.(*ItemTest); ok {
+			decoded++
+		}
+		return badgerhold.DefaultDecode(data, value)
+	}
+
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh, generichold.WithEncoding(badgerhold.DefaultEncode, decode))
+		insertTestData(t, generichold.Open[ItemTest](bh))
+
+		keys, err := store.FindKeys(generichold.Where(itemKey.Gt(testData[10].Key)))
+		ok(t, err)
+		equals(t, len(testData)-11, len(keys))
+
+		count, err := store.CountKeys(generichold.Where(itemCategory.Eq("vehicle")).And(itemKey.Ne(testData[0].Key)))
+		ok(t, err)
+		equals(t, uint64(4), count)
+		equals(t, 0, decoded)
+
+		count, err = store.CountKeys(generichold.Where(itemName.Eq("car")))
+		ok(t, err)
+		equals(t, uint64(1), count)
+		equals(t, len(testData), decoded)
+	})
+}
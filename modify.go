@@ -0,0 +1,90 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"reflect"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Modify reads the record under key, changes it with fn and writes it back in one transaction,
+// returning the written record. On badger.ErrConflict the transaction is run again as configured
+// by WithConflictRetry, so fn may be called more than once. An error from fn leaves the record as it was.
+func (s *store[T]) Modify(key any, fn func(record *T) error) (T, error) {
+	var result T
+	err := s.update(func(tx *badger.Txn) error {
+		var err error
+		result, err = s.TxModify(tx, key, fn)
+		return err
+	})
+	return result, err
+}
+
+func (s *store[T]) TxModify(tx *badger.Txn, key any, fn func(record *T) error) (T, error) {
+	record, err := s.TxGet(tx, key)
+	if err != nil {
+		return record, err
+	}
+
+	err = fn(&record)
+	if err != nil {
+		return record, err
+	}
+
+	return record, s.TxUpdate(tx, key, &record)
+}
+
+// CompareAndSwap writes data under key only if the stored record still equals expected, and reports
+// whether it did. A nil expected swaps only when there is no record under key yet.
+func (s *store[T]) CompareAndSwap(key any, expected, data *T) (bool, error) {
+	var swapped bool
+	err := s.write(data, func(tx *badger.Txn) error {
+		var err error
+		swapped, err = s.TxCompareAndSwap(tx, key, expected, data)
+		return err
+	})
+	return swapped, err
+}
+
+func (s *store[T]) TxCompareAndSwap(tx *badger.Txn, key any, expected, data *T) (bool, error) {
+	current, err := s.TxGet(tx, key)
+	if err == badgerhold.ErrNotFound {
+		if expected != nil {
+			return false, nil
+		}
+		return true, s.TxInsert(tx, key, data)
+	}
+	if err != nil || expected == nil {
+		return false, err
+	}
+
+	// expected goes through the codec and takes the key field of current,
+	// so only what is stored is compared
+	encoded, err := s.options.encode(expected)
+	if err != nil {
+		return false, err
+	}
+	normalized := new(T)
+	err = s.options.decode(encoded, normalized)
+	if err != nil {
+		return false, err
+	}
+	s.loaded(normalized)
+	if s.keyIndex != nil {
+		field := reflect.ValueOf(normalized).Elem().FieldByIndex(s.keyIndex)
+		field.Set(reflect.ValueOf(&current).Elem().FieldByIndex(s.keyIndex))
+	}
+
+	if !reflect.DeepEqual(*normalized, current) {
+		return false, nil
+	}
+	if s.versionIndex != nil {
+		// data replaces current, whatever version it was built with
+		s.version(data).SetUint(s.version(&current).Uint())
+	}
+	return true, s.TxUpdate(tx, key, data)
+}
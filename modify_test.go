@@ -0,0 +1,103 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"sync"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestModify(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[int, ItemTest](bh)
+		ok(t, store.Insert(testData[0].Key, &testData[0]))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := store.Modify(testData[0].Key, func(record *ItemTest) error {
+					record.ID++
+					return nil
+				})
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			ok(t, err)
+		}
+
+		record, err := store.Get(testData[0].Key)
+		ok(t, err)
+		equals(t, testData[0].ID+10, record.ID)
+
+		errAbort := errors.New("abort")
+		_, err = store.Modify(testData[0].Key, func(record *ItemTest) error {
+			record.ID = 0
+			return errAbort
+		})
+		equals(t, errAbort, err)
+
+		modified, err := store.Modify(testData[0].Key, func(record *ItemTest) error {
+			record.Name = "modified"
+			return nil
+		})
+		ok(t, err)
+		equals(t, testData[0].ID+10, modified.ID)
+		equals(t, "modified", modified.Name)
+
+		_, err = store.Modify(testData[1].Key, func(record *ItemTest) error { return nil })
+		equals(t, badgerhold.ErrNotFound, err)
+	})
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+
+		first := KeyedItem{Name: "first"}
+		swapped, err := store.CompareAndSwap(1, nil, &first)
+		ok(t, err)
+		assert(t, swapped, "CompareAndSwap did not insert a missing record")
+
+		swapped, err = store.CompareAndSwap(1, nil, &first)
+		ok(t, err)
+		assert(t, !swapped, "CompareAndSwap overwrote an existing record expected missing")
+
+		current, err := store.Get(1)
+		ok(t, err)
+
+		second := KeyedItem{Name: "second"}
+		swapped, err = store.CompareAndSwap(1, &current, &second)
+		ok(t, err)
+		assert(t, swapped, "CompareAndSwap did not swap the expected record")
+
+		// current is stale now
+		swapped, err = store.CompareAndSwap(1, &current, &KeyedItem{Name: "third"})
+		ok(t, err)
+		assert(t, !swapped, "CompareAndSwap swapped a stale record")
+
+		record, err := store.Get(1)
+		ok(t, err)
+		equals(t, "second", record.Name)
+
+		// the key field does not take part in the comparison
+		swapped, err = store.CompareAndSwap(1, &KeyedItem{Name: "second"}, &KeyedItem{Name: "third"})
+		ok(t, err)
+		assert(t, swapped, "CompareAndSwap compared the key field")
+
+		swapped, err = store.CompareAndSwap(2, &current, &second)
+		ok(t, err)
+		assert(t, !swapped, "CompareAndSwap swapped a missing record")
+	})
+}
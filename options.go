@@ -0,0 +1,133 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// ErrEncodingMismatch is returned by the methods reading or writing badger directly, FindPage or Exists
+// for instance, when the badgerhold store encodes records other than generichold does. WithEncoding
+// must set the Encoder and Decoder of its badgerhold.Options.
+var ErrEncodingMismatch = errors.New("generichold encoding does not match the badgerhold store")
+
+// Option configures a store on Open
+type Option func(o *options)
+
+type options struct {
+	encode badgerhold.EncodeFunc
+	decode badgerhold.DecodeFunc
+
+	retries int
+	backoff func(attempt int) time.Duration
+
+	clock func() time.Time
+
+	// validators are Validator[T] of the T the store is opened for
+	validators []any
+
+	history bool
+
+	// compositeIndexes are the fields of the indexes declared with WithCompositeIndex
+	compositeIndexes [][]string
+
+	// onExpire is the func(record *T) of the T the store is opened for
+	onExpire any
+}
+
+func defaultOptions() options {
+	return options{
+		encode: badgerhold.DefaultEncode,
+		decode: badgerhold.DefaultDecode,
+
+		retries: 10,
+		backoff: func(attempt int) time.Duration {
+			return time.Duration(attempt) * time.Millisecond
+		},
+
+		clock: time.Now,
+	}
+}
+
+// WithEncoding sets the encoding generichold uses to read and write badger directly,
+// it must match the Encoder and Decoder of the badgerhold.Options the store was opened with.
+// Open checks it does, the methods reading badger directly fail with ErrEncodingMismatch otherwise.
+func WithEncoding(encode badgerhold.EncodeFunc, decode badgerhold.DecodeFunc) Option {
+	return func(o *options) {
+		o.encode = encode
+		o.decode = decode
+	}
+}
+
+// WithConflictRetry sets how many times UpdateTx reruns a transaction failing with badger.ErrConflict,
+// and how long it waits before the given attempt.
+func WithConflictRetry(retries int, backoff func(attempt int) time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// WithClock sets where the createdAt and updatedAt fields take the time from, time.Now by default.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.clock = now
+	}
+}
+
+// encodingProbe is the record checkEncoding has badgerhold write
+type encodingProbe struct {
+	Value string
+}
+
+// checkEncoding compares the key and value badgerhold writes for a probe record, in a transaction
+// which is discarded, with the encoding of o. When they differ the encoding is replaced by one failing
+// with ErrEncodingMismatch, so that only the methods reading badger directly fail.
+func (o *options) checkEncoding(s *badgerhold.Store) {
+	tx := s.Badger().NewTransaction(true)
+	defer tx.Discard()
+
+	key, probe := "generichold", encodingProbe{Value: "generichold"}
+	if err := s.TxInsert(tx, key, &probe); err != nil {
+		// a read-only database takes no probe
+		return
+	}
+
+	var decoded encodingProbe
+	encoded, err := o.encode(key)
+	if err == nil {
+		var item *badger.Item
+		item, err = tx.Get(append([]byte("bh_encodingProbe:"), encoded...))
+		if err == nil {
+			err = item.Value(func(value []byte) error {
+				return o.decode(value, &decoded)
+			})
+		}
+	}
+	if err == nil && decoded == probe {
+		return
+	}
+
+	o.encode = func(any) ([]byte, error) {
+		return nil, ErrEncodingMismatch
+	}
+	o.decode = func([]byte, any) error {
+		return ErrEncodingMismatch
+	}
+}
+
+func (o options) retry(fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err != badger.ErrConflict || attempt > o.retries {
+			return err
+		}
+		time.Sleep(o.backoff(attempt))
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// ErrInvalidCursor is returned by FindPage for a cursor it did not produce
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// ErrPagedQuery is returned by FindPage for a query with SortBy, Skip or Limit, which pages can't follow
+var ErrPagedQuery = errors.New("FindPage can't sort, skip or limit a query")
+
+// Page is a single page of FindPage results
+type Page[T any] struct {
+	Items []T
+	// Next is the cursor of the following page, it is empty on the last page
+	Next string
+}
+
+// FindPage returns up to pageSize records matching query in key order, or in the order of the composite
+// index the query is read from, starting after cursor.
+// An empty cursor starts from the first record. Each page seeks straight to its cursor,
+// so the work per page does not grow with its depth the way Query.Skip does.
+// A query with SortBy, Skip or Limit fails with ErrPagedQuery.
+func (s *store[T]) FindPage(query *Query[T], cursor string, pageSize int) (Page[T], error) {
+	var page Page[T]
+	err := s.view(func(tx *badger.Txn) error {
+		var err error
+		page, err = s.TxFindPage(tx, query, cursor, pageSize)
+		return err
+	})
+	return page, err
+}
+
+func (s *store[T]) TxFindPage(tx *badger.Txn, query *Query[T], cursor string, pageSize int) (Page[T], error) {
+	var page Page[T]
+	if err := s.ctxErr(); err != nil {
+		return page, err
+	}
+	if pageSize <= 0 {
+		return page, errors.New("page size must be greater than zero")
+	}
+	if query != nil && (len(query.sort) != 0 || query.skip != 0 || query.limit != 0) {
+		return page, ErrPagedQuery
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return page, ErrInvalidCursor
+	}
+
+	var last []byte
+	found := func(position []byte, record *T) (bool, error) {
+		if len(page.Items) == pageSize {
+			// one more match exists, so there is a next page
+			page.Next = base64.RawURLEncoding.EncodeToString(last)
+			return false, nil
+		}
+
+		page.Items = append(page.Items, *record)
+		last = position
+		return true, nil
+	}
+
+	plan, err := s.compositeFor(tx, query)
+	if err != nil {
+		return page, err
+	}
+	if plan != nil {
+		err = s.scanComposite(tx, plan, query, after, true, func(position, _ []byte, record *T) (bool, error) {
+			return found(position, record)
+		})
+		return page, err
+	}
+
+	err = s.scan(tx, after, func(key []byte, record *T) (bool, error) {
+		ok, err := query.matches(&row[T]{key: key, record: record, decode: s.options.decode})
+		if err != nil || !ok {
+			return err == nil, err
+		}
+		return found(key, record)
+	})
+	return page, err
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"encoding/json"
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestFindPage(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+
+		insertTestData(t, store)
+
+		for _, tst := range typedTestResults {
+			t.Run(tst.name, func(t *testing.T) {
+				if tst.name == "Limit And Skip" {
+					_, err := store.FindPage(tst.query, "", 2)
+					equals(t, generichold.ErrPagedQuery, err)
+					return
+				}
+
+				var result []ItemTest
+				cursor := ""
+				for {
+					page, err := store.FindPage(tst.query, cursor, 2)
+					ok(t, err)
+					assert(t, len(page.Items) <= 2, "Page has %d items, wanted at most 2", len(page.Items))
+					result = append(result, page.Items...)
+					if page.Next == "" {
+						break
+					}
+					cursor = page.Next
+				}
+
+				equals(t, len(tst.result), len(result))
+				for i := range result {
+					found := false
+					for k := range tst.result {
+						if result[i].equal(&testData[tst.result[k]]) {
+							found = true
+							break
+						}
+					}
+					assert(t, found, "%v should not be in the result set!", result[i])
+				}
+			})
+		}
+	})
+}
+
+func TestFindPageKeyOrder(t *testing.T) {
+	opt := testOptions()
+	opt.Encoder = json.Marshal
+	opt.Decoder = json.Unmarshal
+	testWrapWithOpt(t, opt, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh, generichold.WithEncoding(json.Marshal, json.Unmarshal))
+
+		for i := 0; i < 300; i++ {
+			_, err := store.InsertNext(&KeyedItem{Name: "item"})
+			ok(t, err)
+		}
+
+		seen := make(map[uint64]bool)
+		cursor := ""
+		pages := 0
+		for {
+			page, err := store.FindPage(nil, cursor, 50)
+			ok(t, err)
+			pages++
+			for _, item := range page.Items {
+				assert(t, !seen[item.ID], "Key %d returned twice", item.ID)
+				seen[item.ID] = true
+			}
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+		}
+
+		equals(t, 300, len(seen))
+		equals(t, 6, pages)
+
+		_, err := store.FindPage(nil, "not a cursor!", 50)
+		equals(t, generichold.ErrInvalidCursor, err)
+	})
+}
+
+func TestEncodingMismatch(t *testing.T) {
+	opt := testOptions()
+	opt.Encoder = json.Marshal
+	opt.Decoder = json.Unmarshal
+	testWrapWithOpt(t, opt, func(bh *badgerhold.Store, t *testing.T) {
+		// badgerhold reads work without WithEncoding, the ones of generichold fail instead of missing records
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+		ok(t, store.Insert(1, &KeyedItem{Name: "item"}))
+		_, err := store.Get(1)
+		ok(t, err)
+
+		_, err = store.Exists(1)
+		equals(t, generichold.ErrEncodingMismatch, err)
+		_, err = store.FindPage(nil, "", 10)
+		equals(t, generichold.ErrEncodingMismatch, err)
+
+		exists, err := generichold.OpenKeyed[uint64, KeyedItem](bh, generichold.WithEncoding(json.Marshal, json.Unmarshal)).Exists(1)
+		ok(t, err)
+		assert(t, exists, "the record does not exist with the encoding of the store")
+	})
+}
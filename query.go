@@ -0,0 +1,442 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Field names a field of T holding values of type V, e.g. Field[Item, string]("Category").
+type Field[T, V any] string
+
+// Key returns the Field referencing the key of T, stored with values of type K.
+func Key[T, K any]() Field[T, K] {
+	return Field[T, K](badgerhold.Key)
+}
+
+// Criterion is a single typed condition on a field of T.
+type Criterion[T any] struct {
+	field string
+	apply func(c *badgerhold.Criterion) *badgerhold.Query
+	test  func(r *row[T]) (bool, error)
+	// record is set when test reads the whole record, not only the field
+	record bool
+	// op and value are those of an Eq, Gt, Ge, Lt or Le criterion, for composite indexes to seek to
+	op    compareOp
+	value any
+}
+
+// compareOp is the comparison of a Criterion built by Eq, Gt, Ge, Lt or Le
+type compareOp int
+
+const (
+	opNone compareOp = iota
+	opEq
+	opGt
+	opGe
+	opLt
+	opLe
+)
+
+// Query is a type-safe builder which compiles down to a *badgerhold.Query for records of type T.
+// Unlike a *badgerhold.Query it can also be tested against records in memory.
+type Query[T any] struct {
+	criteria []Criterion[T]
+	ors      []*Query[T]
+	index    string
+	skip     int
+	limit    int
+	sort     []string
+	reverse  bool
+}
+
+// row is a decoded record tested against a Query outside of badgerhold
+type row[T any] struct {
+	key    []byte // encoded, without the type prefix
+	record *T
+	decode badgerhold.DecodeFunc
+}
+
+func (f Field[T, V]) Eq(value V) Criterion[T] {
+	return f.compare(value, opEq, func(c int) bool { return c == 0 },
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.Eq(value) })
+}
+
+func (f Field[T, V]) Ne(value V) Criterion[T] {
+	return f.compare(value, opNone, func(c int) bool { return c != 0 },
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.Ne(value) })
+}
+
+func (f Field[T, V]) Gt(value V) Criterion[T] {
+	return f.compare(value, opGt, func(c int) bool { return c > 0 },
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.Gt(value) })
+}
+
+func (f Field[T, V]) Lt(value V) Criterion[T] {
+	return f.compare(value, opLt, func(c int) bool { return c < 0 },
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.Lt(value) })
+}
+
+func (f Field[T, V]) Ge(value V) Criterion[T] {
+	return f.compare(value, opGe, func(c int) bool { return c >= 0 },
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.Ge(value) })
+}
+
+func (f Field[T, V]) Le(value V) Criterion[T] {
+	return f.compare(value, opLe, func(c int) bool { return c <= 0 },
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.Le(value) })
+}
+
+// In accepts a typed slice directly: field.In(values...).
+func (f Field[T, V]) In(values ...V) Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.In(toAny(values)...) },
+		func(value any, _ *T) (bool, error) {
+			return containsAny(value, toAny(values))
+		})
+}
+
+func (f Field[T, V]) IsNil() Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.IsNil() },
+		func(value any, _ *T) (bool, error) {
+			v := reflect.ValueOf(value)
+			switch v.Kind() {
+			case reflect.Invalid:
+				return true, nil
+			case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Chan, reflect.Func:
+				return v.IsNil(), nil
+			}
+			return false, nil
+		})
+}
+
+func (f Field[T, V]) RegExp(expression *regexp.Regexp) Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.RegExp(expression) },
+		func(value any, _ *T) (bool, error) {
+			return expression.Match([]byte(fmt.Sprintf("%s", value))), nil
+		})
+}
+
+func (f Field[T, V]) HasPrefix(prefix string) Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.HasPrefix(prefix) },
+		func(value any, _ *T) (bool, error) {
+			return strings.HasPrefix(fmt.Sprintf("%s", reflect.Indirect(reflect.ValueOf(value))), prefix), nil
+		})
+}
+
+func (f Field[T, V]) HasSuffix(suffix string) Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.HasSuffix(suffix) },
+		func(value any, _ *T) (bool, error) {
+			return strings.HasSuffix(fmt.Sprintf("%s", reflect.Indirect(reflect.ValueOf(value))), suffix), nil
+		})
+}
+
+// Match tests the field value with fn, the typed counterpart of badgerhold's MatchFunc.
+func (f Field[T, V]) Match(fn func(value V, record *T) (bool, error)) Criterion[T] {
+	criterion := f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query {
+			return c.MatchFunc(func(ra *badgerhold.RecordAccess) (bool, error) {
+				value, err := typed[V](ra.Field(), nil)
+				if err != nil {
+					return false, err
+				}
+				record, _ := ra.Record().(*T)
+				return fn(value, record)
+			})
+		},
+		func(value any, record *T) (bool, error) {
+			v, err := typed[V](value, nil)
+			if err != nil {
+				return false, err
+			}
+			return fn(v, record)
+		})
+	criterion.record = true
+	return criterion
+}
+
+func (f Field[T, V]) compare(other V, op compareOp, ok func(c int) bool,
+	apply func(c *badgerhold.Criterion) *badgerhold.Query) Criterion[T] {
+	criterion := f.criterion(apply, func(value any, _ *T) (bool, error) {
+		c, err := compare(value, other)
+		if err != nil {
+			return false, err
+		}
+		return ok(c), nil
+	})
+	criterion.op, criterion.value = op, other
+	return criterion
+}
+
+func (f Field[T, V]) criterion(apply func(c *badgerhold.Criterion) *badgerhold.Query,
+	test func(value any, record *T) (bool, error)) Criterion[T] {
+	return Criterion[T]{
+		field: string(f),
+		apply: apply,
+		test: func(r *row[T]) (bool, error) {
+			value, err := f.value(r)
+			if err != nil {
+				return false, err
+			}
+			return test(value, r.record)
+		},
+	}
+}
+
+// value returns the current value of the field in r
+func (f Field[T, V]) value(r *row[T]) (any, error) {
+	if f == badgerhold.Key {
+		var key V
+		err := r.decode(r.key, &key)
+		return key, err
+	}
+
+	current := reflect.ValueOf(r.record).Elem()
+	for _, name := range strings.Split(string(f), ".") {
+		current = reflect.Indirect(current).FieldByName(name)
+		if !current.IsValid() {
+			return nil, fmt.Errorf("the field %s does not exist in the type %T", f, r.record)
+		}
+	}
+	return current.Interface(), nil
+}
+
+func Contains[T, E any](f Field[T, []E], value E) Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.Contains(value) },
+		func(slice any, _ *T) (bool, error) {
+			values, err := typed[[]E](slice, value)
+			if err != nil {
+				return false, err
+			}
+			return containsAny(value, toAny(values))
+		})
+}
+
+func ContainsAll[T, E any](f Field[T, []E], values ...E) Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.ContainsAll(toAny(values)...) },
+		func(slice any, _ *T) (bool, error) {
+			elements, err := typed[[]E](slice, values)
+			if err != nil {
+				return false, err
+			}
+			for i := range values {
+				found, err := containsAny(values[i], toAny(elements))
+				if err != nil || !found {
+					return false, err
+				}
+			}
+			return true, nil
+		})
+}
+
+func ContainsAny[T, E any](f Field[T, []E], values ...E) Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.ContainsAny(toAny(values)...) },
+		func(slice any, _ *T) (bool, error) {
+			elements, err := typed[[]E](slice, values)
+			if err != nil {
+				return false, err
+			}
+			for i := range values {
+				found, err := containsAny(values[i], toAny(elements))
+				if err != nil || found {
+					return found, err
+				}
+			}
+			return false, nil
+		})
+}
+
+func HasKey[T any, K comparable, V any](f Field[T, map[K]V], key K) Criterion[T] {
+	return f.criterion(
+		func(c *badgerhold.Criterion) *badgerhold.Query { return c.HasKey(key) },
+		func(m any, _ *T) (bool, error) {
+			values, err := typed[map[K]V](m, key)
+			if err != nil {
+				return false, err
+			}
+			_, ok := values[key]
+			return ok, nil
+		})
+}
+
+func Where[T any](criterion Criterion[T]) *Query[T] {
+	return &Query[T]{criteria: []Criterion[T]{criterion}}
+}
+
+func (q *Query[T]) And(criterion Criterion[T]) *Query[T] {
+	q.criteria = append(q.criteria, criterion)
+	return q
+}
+
+func (q *Query[T]) Or(other *Query[T]) *Query[T] {
+	q.ors = append(q.ors, other)
+	return q
+}
+
+func (q *Query[T]) Index(indexName string) *Query[T] {
+	q.index = indexName
+	return q
+}
+
+func (q *Query[T]) Skip(amount int) *Query[T] {
+	q.skip = amount
+	return q
+}
+
+func (q *Query[T]) Limit(amount int) *Query[T] {
+	q.limit = amount
+	return q
+}
+
+func (q *Query[T]) SortBy(fields ...string) *Query[T] {
+	q.sort = append(q.sort, fields...)
+	return q
+}
+
+func (q *Query[T]) Reverse() *Query[T] {
+	q.reverse = !q.reverse
+	return q
+}
+
+// Build returns a new *badgerhold.Query to pass into Store[T] methods.
+// A nil Query builds to nil, which matches every record, and a Query without criteria
+// to an empty *badgerhold.Query with its Skip, Limit and SortBy.
+func (q *Query[T]) Build() *badgerhold.Query {
+	if q == nil {
+		return nil
+	}
+
+	// a Query declared without Where has no criteria, like an empty badgerhold.Query it matches every record
+	query := &badgerhold.Query{}
+	for i, criterion := range q.criteria {
+		if i == 0 {
+			query = criterion.apply(badgerhold.Where(criterion.field))
+		} else {
+			query = criterion.apply(query.And(criterion.field))
+		}
+	}
+	for _, or := range q.ors {
+		query = query.Or(or.Build())
+	}
+
+	if q.index != "" {
+		query = query.Index(q.index)
+	}
+	if q.skip != 0 {
+		query = query.Skip(q.skip)
+	}
+	if q.limit != 0 {
+		query = query.Limit(q.limit)
+	}
+	if len(q.sort) != 0 {
+		query = query.SortBy(q.sort...)
+	}
+	if q.reverse {
+		query = query.Reverse()
+	}
+
+	return query
+}
+
+func (q *Query[T]) String() string {
+	if q == nil {
+		return ""
+	}
+	return q.Build().String()
+}
+
+// matches tests r against the criteria of the query, ignoring index, sort, skip and limit
+func (q *Query[T]) matches(r *row[T]) (bool, error) {
+	if q == nil {
+		return true, nil
+	}
+
+	ok := true
+	for _, criterion := range q.criteria {
+		var err error
+		ok, err = criterion.test(r)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			break
+		}
+	}
+	if ok {
+		return true, nil
+	}
+
+	for _, or := range q.ors {
+		ok, err := or.matches(r)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// fields adds the fields the criteria of q test to fields,
+// it returns true when a criterion reads the whole record instead.
+func (q *Query[T]) fields(fields map[string]struct{}) bool {
+	if q == nil {
+		return false
+	}
+
+	for _, criterion := range q.criteria {
+		if criterion.record {
+			return true
+		}
+		fields[criterion.field] = struct{}{}
+	}
+	for _, or := range q.ors {
+		if or.fields(fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// typed returns the field value as a V, failing with badgerhold.ErrTypeMismatch when the field
+// holds another type, which other is compared with
+func typed[V any](value, other any) (V, error) {
+	v, ok := value.(V)
+	if !ok && value != nil {
+		return v, &badgerhold.ErrTypeMismatch{Value: value, Other: other}
+	}
+	return v, nil
+}
+
+func containsAny(value any, values []any) (bool, error) {
+	for i := range values {
+		c, err := compare(value, values[i])
+		if err != nil {
+			return false, err
+		}
+		if c == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func toAny[V any](values []V) []any {
+	result := make([]any, len(values))
+	for i := range values {
+		result[i] = values[i]
+	}
+	return result
+}
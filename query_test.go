@@ -0,0 +1,165 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+var (
+	itemKey      = generichold.Key[ItemTest, int]()
+	itemID       = generichold.Field[ItemTest, int]("ID")
+	itemName     = generichold.Field[ItemTest, string]("Name")
+	itemCategory = generichold.Field[ItemTest, string]("Category")
+	itemTags     = generichold.Field[ItemTest, []string]("Tags")
+	itemMapVal   = generichold.Field[ItemTest, map[string]string]("MapVal")
+)
+
+var typedTestResults = []struct {
+	name   string
+	query  *generichold.Query[ItemTest]
+	result []int // indices of test data to be found
+}{
+	{
+		name:   "Equal Key",
+		query:  generichold.Where(itemKey.Eq(testData[4].Key)),
+		result: []int{4},
+	},
+	{
+		name:   "Equal Field With Index",
+		query:  generichold.Where(itemCategory.Eq("vehicle")),
+		result: []int{0, 1, 3, 6, 11},
+	},
+	{
+		name:   "And With Index",
+		query:  generichold.Where(itemCategory.Eq("vehicle")).And(itemID.Ge(10)),
+		result: []int{11},
+	},
+	{
+		name:   "Or",
+		query:  generichold.Where(itemName.Eq("van")).Or(generichold.Where(itemID.Gt(10))),
+		result: []int{3, 6, 12, 14, 15},
+	},
+	{
+		name:   "In With Typed Slice",
+		query:  generichold.Where(itemName.In([]string{"car", "truck", "seal"}...)),
+		result: []int{0, 1, 2},
+	},
+	{
+		name:   "Contains",
+		query:  generichold.Where(generichold.Contains(itemTags, "healthy")),
+		result: []int{12, 15},
+	},
+	{
+		name:   "Has Key",
+		query:  generichold.Where(generichold.HasKey(itemMapVal, "test")),
+		result: []int{16},
+	},
+	{
+		name: "Match",
+		query: generichold.Where(itemName.Match(func(value string, record *ItemTest) (bool, error) {
+			return value == "fish" && record.Category == "animal", nil
+		})),
+		result: []int{14},
+	},
+	{
+		name:   "Limit And Skip",
+		query:  generichold.Where(itemCategory.Eq("vehicle")).SortBy("ID").Skip(1).Limit(2),
+		result: []int{1, 3},
+	},
+}
+
+func TestTypedQuery(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+
+		insertTestData(t, store)
+		for _, tst := range typedTestResults {
+			t.Run(tst.name, func(t *testing.T) {
+				result, err := store.Find(tst.query.Build())
+				ok(t, err)
+				equals(t, len(tst.result), len(result))
+
+				for i := range result {
+					found := false
+					for k := range tst.result {
+						if result[i].equal(&testData[tst.result[k]]) {
+							found = true
+							break
+						}
+					}
+					assert(t, found, "%v should not be in the result set!", result[i])
+				}
+			})
+		}
+	})
+}
+
+func TestTypedQueryNil(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+
+		insertTestData(t, store)
+
+		var query *generichold.Query[ItemTest]
+		count, err := store.Count(query.Build())
+		ok(t, err)
+		equals(t, uint64(len(testData)), count)
+	})
+}
+
+func TestTypedQueryEmpty(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+		insertTestData(t, store)
+
+		query := new(generichold.Query[ItemTest])
+		count, err := store.Count(query.Build())
+		ok(t, err)
+		equals(t, uint64(len(testData)), count)
+
+		result, err := store.Find(query.SortBy("Name").Skip(1).Limit(2).Build())
+		ok(t, err)
+		expected, err := store.Find((&badgerhold.Query{}).SortBy("Name").Skip(1).Limit(2))
+		ok(t, err)
+		equals(t, expected, result)
+		equals(t, 2, len(result))
+
+		count, err = store.Count(new(generichold.Query[ItemTest]).Or(generichold.Where(itemName.Eq("nothing"))).Build())
+		ok(t, err)
+		equals(t, uint64(len(testData)), count)
+	})
+}
+
+func TestTypedQueryTypeMismatch(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+		insertTestData(t, store)
+
+		queries := []*generichold.Query[ItemTest]{
+			generichold.Where(generichold.Contains(generichold.Field[ItemTest, []int]("Tags"), 1)),
+			generichold.Where(generichold.ContainsAll(generichold.Field[ItemTest, []int]("Tags"), 1)),
+			generichold.Where(generichold.ContainsAny(generichold.Field[ItemTest, []int]("Tags"), 1)),
+			generichold.Where(generichold.HasKey(generichold.Field[ItemTest, map[int]string]("MapVal"), 1)),
+			generichold.Where(generichold.Field[ItemTest, int64]("ID").Match(func(int64, *ItemTest) (bool, error) {
+				return true, nil
+			})),
+		}
+		for _, query := range queries {
+			// FindPage tests the query in memory
+			_, err := store.FindPage(query, "", 10)
+			var mismatch *badgerhold.ErrTypeMismatch
+			assert(t, errors.As(err, &mismatch), "%s did not fail with ErrTypeMismatch: %v", query, err)
+		}
+
+		_, err := store.Find(queries[len(queries)-1].Build())
+		var mismatch *badgerhold.ErrTypeMismatch
+		assert(t, errors.As(err, &mismatch), "Match did not fail with ErrTypeMismatch in badgerhold: %v", err)
+	})
+}
@@ -0,0 +1,293 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Reducer folds the records of a group into a result of type R one record at a time,
+// read it from the groups of AggregateStream with Result.
+type Reducer[T, R any] struct {
+	start func() reducerState[T]
+	// err is returned by AggregateStream before it reads any record
+	err error
+}
+
+// AnyReducer is a Reducer of T whatever the type of its result.
+type AnyReducer[T any] interface {
+	newState() reducerState[T]
+	check() error
+}
+
+type reducerState[T any] interface {
+	add(record *T) error
+	result() any
+}
+
+// Results are the reducer results of a group, see Reducer.Result
+type Results interface {
+	state(reducer any) any
+}
+
+// NewReducer builds a Reducer which starts every group with the state init returns, folds each record
+// into it with add, and turns the final state into the group result with result.
+func NewReducer[T, S, R any](init func() S, add func(state S, record *T) (S, error), result func(state S) R) *Reducer[T, R] {
+	return &Reducer[T, R]{start: func() reducerState[T] {
+		return &reducerFuncs[T, S, R]{state: init(), addFn: add, resultFn: result}
+	}}
+}
+
+func (r *Reducer[T, R]) newState() reducerState[T] {
+	return r.start()
+}
+
+func (r *Reducer[T, R]) check() error {
+	return r.err
+}
+
+// Result returns the result of r in the group results came from, it panics if r was not passed
+// to the AggregateStream call which returned them.
+func (r *Reducer[T, R]) Result(results Results) R {
+	state, ok := results.state(r).(reducerState[T])
+	if !ok {
+		panic("the reducer is not part of the aggregation")
+	}
+	return state.result().(R)
+}
+
+type reducerFuncs[T, S, R any] struct {
+	state    S
+	addFn    func(state S, record *T) (S, error)
+	resultFn func(state S) R
+}
+
+func (r *reducerFuncs[T, S, R]) add(record *T) error {
+	var err error
+	r.state, err = r.addFn(r.state, record)
+	return err
+}
+
+func (r *reducerFuncs[T, S, R]) result() any {
+	return r.resultFn(r.state)
+}
+
+// StreamGroup is a group of AggregateStream, it keeps the reducer results instead of the records.
+type StreamGroup[G any] struct {
+	group  G
+	count  uint64
+	states map[any]any
+}
+
+func (g *StreamGroup[G]) Group() G {
+	return g.group
+}
+
+func (g *StreamGroup[G]) Count() uint64 {
+	return g.count
+}
+
+func (g *StreamGroup[G]) state(reducer any) any {
+	return g.states[reducer]
+}
+
+// AggregateStream groups the records of T matching query by the value of groupBy like FindAggregateBy,
+// but reads them one at a time with TxForEach and only keeps the state of reducers for each group.
+// Groups are returned in the order of their values. It returns an error when a field of groupBy or of
+// the built in reducers is a field of T of another type.
+func AggregateStream[T, G any](s Store[T], query *badgerhold.Query, groupBy Field[T, G], reducers ...AnyReducer[T]) ([]*StreamGroup[G], error) {
+	var result []*StreamGroup[G]
+	err := s.View(func(tx Tx[T]) error {
+		var err error
+		result, err = TxAggregateStream(s, tx.Txn(), query, groupBy, reducers...)
+		return err
+	})
+	return result, err
+}
+
+func TxAggregateStream[T, G any](s Store[T], tx *badger.Txn, query *badgerhold.Query, groupBy Field[T, G], reducers ...AnyReducer[T]) ([]*StreamGroup[G], error) {
+	if err := checkFieldType(groupBy); err != nil {
+		return nil, err
+	}
+	for _, reducer := range reducers {
+		if err := reducer.check(); err != nil {
+			return nil, err
+		}
+	}
+
+	var groups []*StreamGroup[G]
+	err := s.TxForEach(tx, query, func(record *T) error {
+		group, err := valueOf(groupBy, record)
+		if err != nil {
+			return err
+		}
+
+		// groups are kept sorted, so values which can't be map keys are grouped too
+		var cmpErr error
+		i := sort.Search(len(groups), func(i int) bool {
+			c, err := compare(groups[i].group, group)
+			if err != nil {
+				cmpErr = err
+			}
+			return c >= 0
+		})
+		if cmpErr != nil {
+			return cmpErr
+		}
+
+		if i == len(groups) || !equalGroup(groups[i].group, group) {
+			states := make(map[any]any, len(reducers))
+			for _, reducer := range reducers {
+				states[reducer] = reducer.newState()
+			}
+			groups = slices.Insert(groups, i, &StreamGroup[G]{group: group, states: states})
+		}
+
+		groups[i].count++
+		for _, reducer := range reducers {
+			err := groups[i].states[reducer].(reducerState[T]).add(record)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func equalGroup(group, other any) bool {
+	c, err := compare(group, other)
+	return err == nil && c == 0
+}
+
+// valueOf reads field out of record
+func valueOf[T, V any](field Field[T, V], record *T) (V, error) {
+	var zero V
+	value, err := field.value(&row[T]{record: record})
+	if err != nil {
+		return zero, err
+	}
+	return typed[V](value, zero)
+}
+
+// fieldReducer is r failing when T has a field named field of another type than V
+func fieldReducer[T, V, R any](field Field[T, V], r *Reducer[T, R]) *Reducer[T, R] {
+	r.err = checkFieldType(field)
+	return r
+}
+
+// SumOf adds up field over the records of a group.
+func SumOf[T any, N Number](field Field[T, N]) *Reducer[T, N] {
+	return fieldReducer(field, NewReducer(
+		func() N { return 0 },
+		func(sum N, record *T) (N, error) {
+			value, err := valueOf(field, record)
+			if err != nil {
+				return sum, err
+			}
+			return sum + value, nil
+		},
+		func(sum N) N { return sum }))
+}
+
+// AvgOf is the mean of field over the records of a group.
+func AvgOf[T any, N Number](field Field[T, N]) *Reducer[T, float64] {
+	type avg struct {
+		sum   float64
+		count int
+	}
+	return fieldReducer(field, NewReducer(
+		func() avg { return avg{} },
+		func(state avg, record *T) (avg, error) {
+			value, err := valueOf(field, record)
+			if err != nil {
+				return state, err
+			}
+			return avg{sum: state.sum + float64(value), count: state.count + 1}, nil
+		},
+		func(state avg) float64 {
+			if state.count == 0 {
+				return 0
+			}
+			return state.sum / float64(state.count)
+		}))
+}
+
+// MinOf is the lowest value of field in a group, as badgerhold compares values.
+func MinOf[T, V any](field Field[T, V]) *Reducer[T, V] {
+	return pickOf(field, func(c int) bool { return c < 0 })
+}
+
+// MaxOf is the highest value of field in a group, as badgerhold compares values.
+func MaxOf[T, V any](field Field[T, V]) *Reducer[T, V] {
+	return pickOf(field, func(c int) bool { return c > 0 })
+}
+
+func pickOf[T, V any](field Field[T, V], better func(c int) bool) *Reducer[T, V] {
+	type pick struct {
+		value V
+		set   bool
+	}
+	return fieldReducer(field, NewReducer(
+		func() pick { return pick{} },
+		func(state pick, record *T) (pick, error) {
+			value, err := valueOf(field, record)
+			if err != nil {
+				return state, err
+			}
+			if !state.set {
+				return pick{value: value, set: true}, nil
+			}
+
+			c, err := compare(value, state.value)
+			if err != nil {
+				return state, err
+			}
+			if better(c) {
+				state.value = value
+			}
+			return state, nil
+		},
+		func(state pick) V { return state.value }))
+}
+
+// PercentileOf is the p-th percentile of field in a group, interpolated between the closest ranks.
+// p is between 0 and 100, AggregateStream returns an error otherwise. It keeps every value of field
+// in the group, but not the records.
+func PercentileOf[T any, N Number](field Field[T, N], p float64) *Reducer[T, float64] {
+	reducer := fieldReducer(field, NewReducer(
+		func() []float64 { return nil },
+		func(values []float64, record *T) ([]float64, error) {
+			value, err := valueOf(field, record)
+			if err != nil {
+				return values, err
+			}
+			return append(values, float64(value)), nil
+		},
+		func(values []float64) float64 {
+			if len(values) == 0 {
+				return 0
+			}
+			slices.Sort(values)
+
+			rank := p / 100 * float64(len(values)-1)
+			lower := int(math.Floor(rank))
+			upper := int(math.Ceil(rank))
+			return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+		}))
+	if reducer.err == nil && (p < 0 || p > 100) {
+		reducer.err = fmt.Errorf("percentile %v is not between 0 and 100", p)
+	}
+	return reducer
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestAggregateStream(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+
+		insertTestData(t, store)
+
+		sum := generichold.SumOf(itemID)
+		avg := generichold.AvgOf(itemID)
+		min := generichold.MinOf(itemID)
+		max := generichold.MaxOf(itemID)
+		median := generichold.PercentileOf(itemID, 50)
+		names := generichold.NewReducer(
+			func() []string { return nil },
+			func(names []string, record *ItemTest) ([]string, error) {
+				return append(names, record.Name), nil
+			},
+			func(names []string) []string { return names })
+
+		result, err := generichold.AggregateStream(store, nil, itemCategory, sum, avg, min, max, median, names)
+		ok(t, err)
+		equals(t, 3, len(result))
+
+		expected, err := generichold.FindAggregateBy(store, nil, itemCategory)
+		ok(t, err)
+
+		for i := range result {
+			assert(t, i == 0 || result[i-1].Group() < result[i].Group(), "groups are not in order")
+
+			var want *generichold.Aggregate[ItemTest, string]
+			for _, aggregate := range expected {
+				if aggregate.Group() == result[i].Group() {
+					want = aggregate
+				}
+			}
+			assert(t, want != nil, "Unaccounted for grouping: %s", result[i].Group())
+
+			equals(t, want.Count(), result[i].Count())
+			wantSum, err := generichold.Sum(want, itemID)
+			ok(t, err)
+			equals(t, wantSum, sum.Result(result[i]))
+			wantAvg, err := generichold.Avg(want, itemID)
+			ok(t, err)
+			equals(t, wantAvg, avg.Result(result[i]))
+			wantMin, err := want.Min(itemID)
+			ok(t, err)
+			equals(t, wantMin.ID, min.Result(result[i]))
+			wantMax, err := want.Max(itemID)
+			ok(t, err)
+			equals(t, wantMax.ID, max.Result(result[i]))
+			equals(t, int(want.Count()), len(names.Result(result[i])))
+		}
+
+		result, err = generichold.AggregateStream(store, badgerhold.Where("Category").Eq("food"), itemCategory, median)
+		ok(t, err)
+		equals(t, 1, len(result))
+		// food IDs are 5, 8, 9, 11 and 13
+		equals(t, 9.0, median.Result(result[0]))
+
+		func() {
+			defer func() {
+				assert(t, recover() != nil, "Result did not panic on a reducer missing from the aggregation")
+			}()
+			sum.Result(result[0])
+		}()
+
+		// mismatched fields and percentiles fail before any record is read
+		for _, reducer := range []generichold.AnyReducer[ItemTest]{
+			generichold.SumOf(generichold.Field[ItemTest, int64]("ID")),
+			generichold.AvgOf(generichold.Field[ItemTest, float64]("ID")),
+			generichold.MaxOf(generichold.Field[ItemTest, int]("Category")),
+			generichold.PercentileOf(generichold.Field[ItemTest, uint]("ID"), 50),
+			generichold.PercentileOf(itemID, 101),
+		} {
+			_, err = generichold.AggregateStream(store, nil, itemCategory, reducer)
+			assert(t, err != nil, "AggregateStream accepted %T", reducer)
+		}
+		_, err = generichold.AggregateStream(store, nil, generichold.Field[ItemTest, int]("Category"), sum)
+		assert(t, err != nil, "AggregateStream accepted a group field of the wrong type")
+	})
+}
@@ -0,0 +1,235 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// scan decodes the records of T in key order, starting after the encoded key after when it is set,
+// leaving out the expired ones.
+// badgerhold can't do this, its queries always iterate from the first key of the type.
+func (s *store[T]) scan(tx *badger.Txn, after []byte, fn func(key []byte, record *T) (bool, error)) error {
+	return s.scanPrefix(tx, s.prefix, after, nil, func(_, key []byte, record *T) (bool, error) {
+		if s.expired(record) {
+			return true, nil
+		}
+		return fn(key, record)
+	})
+}
+
+// scanPrefix is scan over the records stored under prefix, passing fn the entries after prefix along with the keys.
+// recordKey cuts the record key off an entry, when the entry is more than the key.
+func (s *store[T]) scanPrefix(tx *badger.Txn, prefix, after []byte, recordKey func(entry []byte) ([]byte, error),
+	fn func(entry, key []byte, record *T) (bool, error)) error {
+	iter := tx.NewIterator(badger.DefaultIteratorOptions)
+	defer iter.Close()
+
+	iter.Seek(append(prefix[:len(prefix):len(prefix)], after...))
+	if len(after) != 0 && iter.ValidForPrefix(prefix) && bytes.Equal(iter.Item().Key()[len(prefix):], after) {
+		iter.Next()
+	}
+
+	for ; iter.ValidForPrefix(prefix); iter.Next() {
+		if err := s.ctxErr(); err != nil {
+			return err
+		}
+
+		item := iter.Item()
+		entry := item.KeyCopy(nil)[len(prefix):]
+		key := entry
+		if recordKey != nil {
+			var err error
+			key, err = recordKey(entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		var record *T
+		err := item.Value(func(value []byte) error {
+			var err error
+			record, err = s.decodeRecord(key, value)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		next, err := fn(entry, key, record)
+		if err != nil || !next {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeRecord decodes a stored value and sets its key field like badgerhold reads do, then runs AfterLoad
+func (s *store[T]) decodeRecord(key, value []byte) (*T, error) {
+	record, err := s.decodeStored(key, value)
+	if err != nil {
+		return nil, err
+	}
+	s.loaded(record)
+	return record, nil
+}
+
+// decodeStored is decodeRecord without AfterLoad, for the record as it was written
+func (s *store[T]) decodeStored(key, value []byte) (*T, error) {
+	record := new(T)
+	err := s.options.decode(value, record)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.keyIndex != nil {
+		err = s.options.decode(key, reflect.ValueOf(record).Elem().FieldByIndex(s.keyIndex).Addr().Interface())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// FindWhere returns the records matching a typed query, honoring its Skip, Limit and SortBy.
+// A query a composite index can seek in is read from it, as FindKeys reads it.
+func (s *store[T]) FindWhere(query *Query[T]) ([]T, error) {
+	var result []T
+	err := s.view(func(tx *badger.Txn) error {
+		var err error
+		result, err = s.TxFindWhere(tx, query)
+		return err
+	})
+	return result, err
+}
+
+func (s *store[T]) TxFindWhere(tx *badger.Txn, query *Query[T]) ([]T, error) {
+	var result []T
+	err := s.forEachMatch(tx, query, func(_ []byte, record *T) error {
+		result = append(result, *record)
+		return nil
+	})
+	return result, err
+}
+
+// forEachMatch calls fn with the encoded key and the record of every match of query, honoring its Skip
+// and Limit. Matches come in key order, or in the order of the composite index the query is read from,
+// or when query has SortBy, sorted the way badgerhold sorts them once they are all read.
+func (s *store[T]) forEachMatch(tx *badger.Txn, query *Query[T], fn func(key []byte, record *T) error) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+
+	skip, limit, found := 0, 0, 0
+	if query != nil {
+		skip, limit = query.skip, query.limit
+	}
+	emit := func(key []byte, record *T) (bool, error) {
+		if skip > 0 {
+			skip--
+			return true, nil
+		}
+		if err := fn(key, record); err != nil {
+			return false, err
+		}
+		found++
+		return limit <= 0 || found < limit, nil
+	}
+
+	sorted := query != nil && len(query.sort) != 0
+	var matches []match[T]
+	collect := func(key []byte, record *T) (bool, error) {
+		if sorted {
+			matches = append(matches, match[T]{key: key, record: record})
+			return true, nil
+		}
+		return emit(key, record)
+	}
+
+	plan, err := s.compositeFor(tx, query)
+	if err != nil {
+		return err
+	}
+	if plan != nil {
+		err = s.scanComposite(tx, plan, query, nil, true, func(_, key []byte, record *T) (bool, error) {
+			return collect(key, record)
+		})
+		// equal matches are sorted in key order, like the ones of a scan
+		slices.SortFunc(matches, func(a, b match[T]) int { return bytes.Compare(a.key, b.key) })
+	} else {
+		err = s.scan(tx, nil, func(key []byte, record *T) (bool, error) {
+			ok, err := query.matches(&row[T]{key: key, record: record, decode: s.options.decode})
+			if err != nil || !ok {
+				return err == nil, err
+			}
+			return collect(key, record)
+		})
+	}
+	if err != nil || !sorted {
+		return err
+	}
+
+	err = query.sortMatches(matches)
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		next, err := emit(m.key, m.record)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+// match is a record read by forEachMatch, under its encoded key
+type match[T any] struct {
+	key    []byte
+	record *T
+}
+
+// sortMatches sorts matches by the SortBy fields of q, keeping the key order of equal ones
+func (q *Query[T]) sortMatches(matches []match[T]) error {
+	var err error
+	slices.SortStableFunc(matches, func(a, b match[T]) int {
+		for _, field := range q.sort {
+			name := Field[T, any](field)
+			value, e := name.value(&row[T]{record: a.record})
+			if e != nil {
+				err = e
+				return 0
+			}
+			other, e := name.value(&row[T]{record: b.record})
+			if e != nil {
+				err = e
+				return 0
+			}
+
+			c, e := compare(value, other)
+			if e != nil {
+				err = e
+				return 0
+			}
+			if c != 0 {
+				if q.reverse {
+					return -c
+				}
+				return c
+			}
+		}
+		return 0
+	})
+	return err
+}
+
+// decodeKey decodes the key of a record, encoded as badgerhold stores it without the type prefix
+func (s *store[T]) decodeKey(encoded []byte, key any) error {
+	return s.options.decode(encoded, key)
+}
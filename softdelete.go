@@ -0,0 +1,245 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"encoding/binary"
+	"reflect"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// purgeBatch is how many soft deleted records Purge removes per transaction
+const purgeBatch = 1000
+
+// DeletedView reads the records of a store along with the ones it soft deleted. Queries are tested
+// in memory and results come in key order, the stored records before the deleted ones.
+// The query's SortBy is ignored.
+type DeletedView[T any] interface {
+	Count(query *Query[T]) (uint64, error)
+	Find(query *Query[T]) ([]T, error)
+	ForEach(query *Query[T], fn func(record *T) error) error
+	Get(key any) (T, error)
+	TxCount(tx *badger.Txn, query *Query[T]) (uint64, error)
+	TxFind(tx *badger.Txn, query *Query[T]) ([]T, error)
+	TxForEach(tx *badger.Txn, query *Query[T], fn func(record *T) error) error
+	TxGet(tx *badger.Txn, key any) (T, error)
+}
+
+type deletedView[T any] struct {
+	store *store[T]
+}
+
+// WithDeleted returns a view of the store which reads soft deleted records too.
+// A type with a time.Time field tagged `generichold:"deletedAt"` is soft deleted: Delete and DeleteMatching
+// set the field and move the record out of badgerhold's reach, so that badgerhold reads and indexes
+// no longer see it, and only Purge removes it for good. A key deleted again, after a record was inserted
+// under it, keeps every deleted record; Get returns the last one.
+func (s *store[T]) WithDeleted() DeletedView[T] {
+	return &deletedView[T]{store: s}
+}
+
+// Purge removes the records soft deleted more than olderThan ago.
+// It commits every purgeBatch records, so a failing Purge may have removed some of them.
+func (s *store[T]) Purge(olderThan time.Duration) error {
+	if s.deletedIndex == nil {
+		return nil
+	}
+
+	before := s.options.clock().Add(-olderThan)
+	var after []byte
+	for {
+		var last []byte
+		var purged [][]byte
+		err := s.update(func(tx *badger.Txn) error {
+			last, purged = after, nil
+			err := s.scanPrefix(tx, s.deletedPrefix, after, deletedRecordKey, func(entry, _ []byte, record *T) (bool, error) {
+				if len(purged) == purgeBatch {
+					return false, nil
+				}
+				last = entry
+				if !s.deletedAt(record).After(before) {
+					purged = append(purged, entry)
+				}
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range purged {
+				err = tx.Delete(append(s.deletedPrefix[:len(s.deletedPrefix):len(s.deletedPrefix)], entry...))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil || len(purged) < purgeBatch {
+			return err
+		}
+		after = last
+	}
+}
+
+// softDelete moves the record stored under key to the deleted records with its deletedAt field set
+func (s *store[T]) softDelete(tx *badger.Txn, key any) error {
+	record, err := s.get(tx, key)
+	if err == nil {
+		err = s.retire(tx, key, &record)
+	}
+	if err == nil {
+		err = s.store.TxDelete(tx, key, s.zeroValue())
+	}
+	if err == nil {
+		err = s.keepDeleted(tx, key, &record)
+	}
+	return err
+}
+
+// keepDeleted adds record, just deleted from under key, to the deleted records with its deletedAt field set.
+// The deleted records of a key are numbered after the prefix of the key, so none replaces another.
+func (s *store[T]) keepDeleted(tx *badger.Txn, key any, record *T) error {
+	reflect.ValueOf(record).Elem().FieldByIndex(s.deletedIndex).Set(reflect.ValueOf(s.options.clock()))
+	value, err := s.options.encode(record)
+	if err != nil {
+		return err
+	}
+	prefix, err := s.keyPrefix(s.deletedPrefix, key)
+	if err != nil {
+		return err
+	}
+	return tx.Set(binary.BigEndian.AppendUint64(prefix, lastNumber(tx, prefix)+1), value)
+}
+
+// deletedRecordKey cuts the encoded record key off the entry of a deleted record
+func deletedRecordKey(entry []byte) ([]byte, error) {
+	key, _, err := cutPart(entry)
+	return key, err
+}
+
+func (s *store[T]) deletedAt(record *T) time.Time {
+	return reflect.ValueOf(record).Elem().FieldByIndex(s.deletedIndex).Interface().(time.Time)
+}
+
+func (v *deletedView[T]) Count(query *Query[T]) (uint64, error) {
+	var count uint64
+	err := v.store.view(func(tx *badger.Txn) error {
+		var err error
+		count, err = v.TxCount(tx, query)
+		return err
+	})
+	return count, err
+}
+
+func (v *deletedView[T]) Find(query *Query[T]) ([]T, error) {
+	var result []T
+	err := v.store.view(func(tx *badger.Txn) error {
+		var err error
+		result, err = v.TxFind(tx, query)
+		return err
+	})
+	return result, err
+}
+
+func (v *deletedView[T]) ForEach(query *Query[T], fn func(record *T) error) error {
+	return v.store.view(func(tx *badger.Txn) error {
+		return v.TxForEach(tx, query, fn)
+	})
+}
+
+func (v *deletedView[T]) Get(key any) (T, error) {
+	var result T
+	err := v.store.view(func(tx *badger.Txn) error {
+		var err error
+		result, err = v.TxGet(tx, key)
+		return err
+	})
+	return result, err
+}
+
+func (v *deletedView[T]) TxCount(tx *badger.Txn, query *Query[T]) (uint64, error) {
+	var count uint64
+	err := v.TxForEach(tx, query, func(*T) error {
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (v *deletedView[T]) TxFind(tx *badger.Txn, query *Query[T]) ([]T, error) {
+	var result []T
+	err := v.TxForEach(tx, query, func(record *T) error {
+		result = append(result, *record)
+		return nil
+	})
+	return result, err
+}
+
+// TxForEach honors the Skip and Limit of query across the stored and the deleted records
+func (v *deletedView[T]) TxForEach(tx *badger.Txn, query *Query[T], fn func(record *T) error) error {
+	skip, limit := 0, 0
+	if query != nil {
+		skip, limit = query.skip, query.limit
+	}
+
+	matches := 0
+	each := func(key []byte, record *T) (bool, error) {
+		ok, err := query.matches(&row[T]{key: key, record: record, decode: v.store.options.decode})
+		if err != nil || !ok {
+			return err == nil, err
+		}
+
+		matches++
+		if matches <= skip {
+			return true, nil
+		}
+		err = fn(record)
+		return err == nil && (limit == 0 || matches < skip+limit), err
+	}
+
+	err := v.store.scan(tx, nil, each)
+	if err != nil || (limit != 0 && matches >= skip+limit) || v.store.deletedIndex == nil {
+		return err
+	}
+	return v.store.scanPrefix(tx, v.store.deletedPrefix, nil, deletedRecordKey, func(_, key []byte, record *T) (bool, error) {
+		return each(key, record)
+	})
+}
+
+func (v *deletedView[T]) TxGet(tx *badger.Txn, key any) (T, error) {
+	result, err := v.store.TxGet(tx, key)
+	if err != badgerhold.ErrNotFound || v.store.deletedIndex == nil {
+		return result, err
+	}
+
+	prefix, err := v.store.keyPrefix(v.store.deletedPrefix, key)
+	if err != nil {
+		return result, err
+	}
+	last := lastNumber(tx, prefix)
+	if last == 0 {
+		return result, badgerhold.ErrNotFound
+	}
+	item, err := tx.Get(binary.BigEndian.AppendUint64(prefix, last))
+	if err != nil {
+		return result, err
+	}
+
+	err = item.Value(func(value []byte) error {
+		encoded, err := deletedRecordKey(prefix[len(v.store.deletedPrefix):])
+		if err != nil {
+			return err
+		}
+		record, err := v.store.decodeRecord(encoded, value)
+		if err == nil {
+			result = *record
+		}
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"github.com/rlshukhov/generichold"
+	"testing"
+	"time"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type DeletableItem struct {
+	ID       uint64    `badgerhold:"key"`
+	Category string    `badgerholdIndex:"Category"`
+	Deleted  time.Time `generichold:"deletedAt"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := generichold.OpenKeyed[uint64, DeletableItem](bh, generichold.WithClock(func() time.Time { return now }))
+
+		for i := uint64(1); i <= 4; i++ {
+			category := "blue"
+			if i%2 == 0 {
+				category = "red"
+			}
+			ok(t, store.Insert(i, &DeletableItem{Category: category}))
+		}
+
+		ok(t, store.Delete(1))
+		now = now.Add(time.Hour)
+		ok(t, store.DeleteMatching(badgerhold.Where("Category").Eq("red")))
+		equals(t, badgerhold.ErrNotFound, store.Delete(1))
+
+		// every read leaves the deleted records out, the index included
+		_, err := store.Get(1)
+		equals(t, badgerhold.ErrNotFound, err)
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(1), count)
+		found, err := store.Find(badgerhold.Where("Category").Eq("red").Index("Category"))
+		ok(t, err)
+		equals(t, 0, len(found))
+		groups, err := store.FindAggregate(nil, "Category")
+		ok(t, err)
+		equals(t, 1, len(groups))
+
+		deleted := store.WithDeleted()
+		record, err := deleted.Get(uint64(1))
+		ok(t, err)
+		equals(t, uint64(1), record.ID)
+		equals(t, now.Add(-time.Hour), record.Deleted.UTC())
+
+		category := generichold.Field[DeletableItem, string]("Category")
+		all, err := deleted.Find(nil)
+		ok(t, err)
+		equals(t, 4, len(all))
+		count, err = deleted.Count(generichold.Where(category.Eq("red")))
+		ok(t, err)
+		equals(t, uint64(2), count)
+		page, err := deleted.Find(generichold.Where(category.In("blue", "red")).Skip(1).Limit(2))
+		ok(t, err)
+		equals(t, []uint64{1, 2}, []uint64{page[0].ID, page[1].ID})
+		// deleted records are matched on their own keys
+		key := generichold.Key[DeletableItem, uint64]()
+		byKey, err := deleted.Find(generichold.Where(key.Eq(2)))
+		ok(t, err)
+		equals(t, 1, len(byKey))
+		equals(t, uint64(2), byKey[0].ID)
+		count, err = deleted.Count(generichold.Where(key.Ge(3)))
+		ok(t, err)
+		equals(t, uint64(2), count)
+
+		// only the record deleted two hours ago is old enough
+		now = now.Add(time.Hour)
+		ok(t, store.Purge(90*time.Minute))
+		_, err = deleted.Get(uint64(1))
+		equals(t, badgerhold.ErrNotFound, err)
+		_, err = deleted.Get(uint64(2))
+		ok(t, err)
+
+		// inserting under a deleted key keeps the deleted record, and so does deleting it again
+		ok(t, store.Insert(2, &DeletableItem{Category: "green"}))
+		all, err = deleted.Find(nil)
+		ok(t, err)
+		equals(t, 4, len(all))
+		ok(t, store.Delete(2))
+		all, err = deleted.Find(nil)
+		ok(t, err)
+		equals(t, 4, len(all))
+		record, err = deleted.Get(uint64(2))
+		ok(t, err)
+		equals(t, "green", record.Category)
+
+		ok(t, store.Purge(0))
+		all, err = deleted.Find(nil)
+		ok(t, err)
+		equals(t, 1, len(all))
+	})
+}
+
+func TestSoftDeleteMatchingWithoutKey(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		type NoKeyItem struct {
+			Name    string
+			Deleted time.Time `generichold:"deletedAt"`
+		}
+
+		store := generichold.Open[NoKeyItem](bh)
+		ok(t, store.Insert(1, &NoKeyItem{Name: "first"}))
+		ok(t, store.Insert(2, &NoKeyItem{Name: "second"}))
+		ok(t, store.DeleteMatching(badgerhold.Where("Name").Eq("second")))
+
+		// a record written in the same transaction is kept too
+		ok(t, store.UpdateTx(func(tx generichold.Tx[NoKeyItem]) error {
+			err := tx.Insert(3, &NoKeyItem{Name: "third"})
+			if err == nil {
+				err = tx.DeleteMatching(badgerhold.Where("Name").Eq("third"))
+			}
+			return err
+		}))
+
+		records, err := store.Find(nil)
+		ok(t, err)
+		equals(t, []string{"first"}, []string{records[0].Name})
+		equals(t, 1, len(records))
+
+		deleted := store.WithDeleted()
+		for key, name := range map[int]string{2: "second", 3: "third"} {
+			record, err := deleted.Get(key)
+			ok(t, err)
+			equals(t, name, record.Name)
+			assert(t, !record.Deleted.IsZero(), "%s was not marked deleted", name)
+		}
+		count, err := deleted.Count(nil)
+		ok(t, err)
+		equals(t, uint64(3), count)
+	})
+}
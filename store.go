@@ -5,153 +5,555 @@
 package generichold
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
 	"github.com/dgraph-io/badger/v4"
 	"github.com/timshannon/badgerhold/v4"
 )
 
 type store[T any] struct {
-	store *badgerhold.Store
+	store    *badgerhold.Store
+	options  options
+	prefix   []byte
+	keyIndex []int
+	// versionIndex, createdIndex and updatedIndex are the fields tagged
+	// `generichold:"version"`, `generichold:"createdAt"` and `generichold:"updatedAt"`
+	versionIndex []int
+	createdIndex []int
+	updatedIndex []int
+	// deletedIndex is the field tagged `generichold:"deletedAt"`, soft deleted records are kept under deletedPrefix
+	deletedIndex  []int
+	deletedPrefix []byte
+	// expiresIndex is the field tagged `generichold:"expiresAt"`, onExpire is set by WithOnExpire
+	expiresIndex []int
+	onExpire     func(record *T)
+	// historyPrefix is where WithHistory keeps the revisions of records
+	historyPrefix []byte
+	uniqueGroups  []uniqueGroup
+	// compositeIndexes are the indexes declared with WithCompositeIndex
+	compositeIndexes []compositeIndex
+	hooks            hooks
+	validators       []Validator[T]
+	// ctx is set on the views returned by WithContext
+	ctx context.Context
+	// err is the misconfiguration Open found, every method returns it
+	err error
 }
 
 type Store[T any] interface {
+	AsOf(ts uint64) ReadOnlyStore[T]
 	FindAggregate(query *badgerhold.Query, groupBy ...string) ([]*badgerhold.AggregateResult, error)
 	TxFindAggregate(tx *badger.Txn, query *badgerhold.Query, groupBy ...string) ([]*badgerhold.AggregateResult, error)
 	Delete(key any) error
 	DeleteMatching(query *badgerhold.Query) error
 	TxDelete(tx *badger.Txn, key any) error
 	TxDeleteMatching(tx *badger.Txn, query *badgerhold.Query) error
+	CompareAndSwap(key any, expected, data *T) (bool, error)
 	Count(query *badgerhold.Query) (uint64, error)
+	CountKeys(query *Query[T]) (uint64, error)
+	Exists(key any) (bool, error)
 	Find(query *badgerhold.Query) ([]T, error)
 	FindOne(query *badgerhold.Query) (T, error)
-	ForEach(query *badgerhold.Query, fn any) error
+	FindPage(query *Query[T], cursor string, pageSize int) (Page[T], error)
+	FindWhere(query *Query[T]) ([]T, error)
+	ForEach(query *badgerhold.Query, fn func(record *T) error) error
 	Get(key any) (T, error)
+	History(key any) ([]Revision[T], error)
+	TxCompareAndSwap(tx *badger.Txn, key any, expected, data *T) (bool, error)
 	TxCount(tx *badger.Txn, query *badgerhold.Query) (uint64, error)
+	TxCountKeys(tx *badger.Txn, query *Query[T]) (uint64, error)
+	TxExists(tx *badger.Txn, key any) (bool, error)
 	TxFind(tx *badger.Txn, query *badgerhold.Query) ([]T, error)
 	TxFindOne(tx *badger.Txn, query *badgerhold.Query) (T, error)
-	TxForEach(tx *badger.Txn, query *badgerhold.Query, fn any) error
+	TxFindPage(tx *badger.Txn, query *Query[T], cursor string, pageSize int) (Page[T], error)
+	TxFindWhere(tx *badger.Txn, query *Query[T]) ([]T, error)
+	TxForEach(tx *badger.Txn, query *badgerhold.Query, fn func(record *T) error) error
 	TxGet(tx *badger.Txn, key any) (T, error)
+	TxHistory(tx *badger.Txn, key any) ([]Revision[T], error)
 	Insert(key any, data *T) error
+	InsertWithTTL(key any, data *T, ttl time.Duration) error
+	Modify(key any, fn func(record *T) error) (T, error)
+	Purge(olderThan time.Duration) error
+	Revert(key any, revision uint64) error
 	TxInsert(tx *badger.Txn, key any, data *T) error
+	TxInsertWithTTL(tx *badger.Txn, key any, data *T, ttl time.Duration) error
+	TxModify(tx *badger.Txn, key any, fn func(record *T) error) (T, error)
+	TxRevert(tx *badger.Txn, key any, revision uint64) error
 	TxUpdate(tx *badger.Txn, key any, data *T) error
 	TxUpdateMatching(tx *badger.Txn, query *badgerhold.Query, update func(record *T) error) error
 	TxUpsert(tx *badger.Txn, key any, data *T) error
 	Update(key any, data *T) error
 	UpdateMatching(query *badgerhold.Query, update func(record *T) error) error
 	Upsert(key any, data *T) error
+	View(fn func(tx Tx[T]) error) error
+	UpdateTx(fn func(tx Tx[T]) error) error
+	Watch(ctx context.Context, query *Query[T]) (<-chan Change[T], error)
+	Bind(tx *Txn) Tx[T]
+	Snapshot() SnapshotStore[T]
+	StartSweeper(ctx context.Context, interval time.Duration) <-chan error
+	SweepExpired() error
+	WithContext(ctx context.Context) Store[T]
+	WithDeleted() DeletedView[T]
 	Badger() *badger.DB
 	Close() error
 }
 
-func Open[T any](s *badgerhold.Store) Store[T] {
-	return &store[T]{store: s}
+// Open returns the store of the records of T in s. When opts do not fit T, e.g. a validator of another type
+// or a composite index of a missing field, every method of the store returns the error.
+func Open[T any](s *badgerhold.Store, opts ...Option) Store[T] {
+	return newStore[T](s, opts)
+}
+
+func newStore[T any](s *badgerhold.Store, opts []Option) *store[T] {
+	result := &store[T]{
+		store:   s,
+		options: defaultOptions(),
+		prefix:  []byte("bh_" + typeName[T]() + ":"),
+		// badgerhold keys all start with "bh_" or "_bh", so it never reads these
+		deletedPrefix: []byte("gh_deleted:" + typeName[T]() + ":"),
+		historyPrefix: []byte("gh_history:" + typeName[T]() + ":"),
+	}
+	for _, opt := range opts {
+		opt(&result.options)
+	}
+	result.options.checkEncoding(s)
+	result.hooks = hooksOf[T]()
+
+	var errs []error
+	var err error
+	result.validators, err = validatorsOf[T](result.options)
+	errs = append(errs, err)
+	result.onExpire, err = onExpireOf[T](result.options)
+	errs = append(errs, err)
+	result.compositeIndexes, err = compositeIndexesOf[T](result.options, reflect.TypeOf(result.zeroValue()))
+	errs = append(errs, err)
+
+	if tp := reflect.TypeOf(result.zeroValue()); tp != nil && tp.Kind() == reflect.Struct {
+		if field, ok := keyField(tp); ok {
+			result.keyIndex = field.Index
+		}
+		managed := func(name string, fieldType reflect.Type) []int {
+			index, err := managedField(tp, name, fieldType)
+			errs = append(errs, err)
+			return index
+		}
+		result.versionIndex = managed("version", reflect.TypeOf(uint64(0)))
+		result.createdIndex = managed("createdAt", timeType)
+		result.updatedIndex = managed("updatedAt", timeType)
+		result.deletedIndex = managed("deletedAt", timeType)
+		result.expiresIndex = managed("expiresAt", timeType)
+		result.uniqueGroups = uniqueGroupsOf(tp)
+	}
+
+	// a read-only database can't build the indexes, reads leave out the ones not built
+	if err := errors.Join(errs...); err != nil || s.Badger().Opts().ReadOnly {
+		result.err = err
+		return result
+	}
+	for i := range result.compositeIndexes {
+		errs = append(errs, result.buildComposite(&result.compositeIndexes[i]))
+	}
+	for _, group := range result.uniqueGroups {
+		errs = append(errs, result.buildUnique(group))
+	}
+	result.err = errors.Join(errs...)
+	return result
 }
 
 func (s *store[T]) FindAggregate(query *badgerhold.Query, groupBy ...string) ([]*badgerhold.AggregateResult, error) {
+	if s.checked() {
+		var result []*badgerhold.AggregateResult
+		err := s.view(func(tx *badger.Txn) error {
+			var err error
+			result, err = s.TxFindAggregate(tx, query, groupBy...)
+			return err
+		})
+		return result, err
+	}
 	return s.store.FindAggregate(s.zeroValue(), query, groupBy...)
 }
 
 func (s *store[T]) TxFindAggregate(tx *badger.Txn, query *badgerhold.Query, groupBy ...string) ([]*badgerhold.AggregateResult, error) {
-	return s.store.TxFindAggregate(tx, s.zeroValue(), query, groupBy...)
+	if err := s.ctxErr(); err != nil {
+		return nil, err
+	}
+	result, err := s.store.TxFindAggregate(tx, s.zeroValue(), query, groupBy...)
+	if err == nil {
+		err = s.ctxErr()
+	}
+	return result, err
 }
 
 func (s *store[T]) Delete(key any) error {
+	if !s.plain() {
+		return s.update(func(tx *badger.Txn) error {
+			return s.TxDelete(tx, key)
+		})
+	}
 	return s.store.Delete(key, s.zeroValue())
 }
 
 func (s *store[T]) DeleteMatching(query *badgerhold.Query) error {
+	if !s.plain() {
+		return s.update(func(tx *badger.Txn) error {
+			return s.TxDeleteMatching(tx, query)
+		})
+	}
 	return s.store.DeleteMatching(s.zeroValue(), query)
 }
 
 func (s *store[T]) TxDelete(tx *badger.Txn, key any) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+	if s.deletedIndex != nil {
+		return s.softDelete(tx, key)
+	}
+
+	if s.hooks.beforeDelete || s.options.history || s.indexed() {
+		record, err := s.get(tx, key)
+		if err != nil && err != badgerhold.ErrNotFound {
+			return err
+		}
+		if err == nil {
+			err = s.retire(tx, key, &record)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	return s.store.TxDelete(tx, key, s.zeroValue())
 }
 
 func (s *store[T]) TxDeleteMatching(tx *badger.Txn, query *badgerhold.Query) error {
-	return s.store.TxDeleteMatching(tx, s.zeroValue(), query)
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+	if s.deletedIndex != nil || s.options.history || len(s.compositeIndexes) != 0 {
+		err := s.deleteEach(tx, query)
+		if err == nil {
+			err = s.ctxErr()
+		}
+		return err
+	}
+	if s.hooks.beforeDelete || len(s.uniqueGroups) != 0 {
+		// badgerhold deletes the matches without calling back, so they are read first
+		err := s.forEach(tx, query, func(record *T) error {
+			err := s.beforeDelete(record)
+			if err == nil {
+				err = s.dropUnique(tx, record)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// ctx is checked again once the matches are gone
+	err := s.store.TxDeleteMatching(tx, s.zeroValue(), query)
+	if err == nil {
+		err = s.ctxErr()
+	}
+	return err
 }
 
 func (s *store[T]) Count(query *badgerhold.Query) (uint64, error) {
+	if s.checked() {
+		var result uint64
+		err := s.view(func(tx *badger.Txn) error {
+			var err error
+			result, err = s.TxCount(tx, query)
+			return err
+		})
+		return result, err
+	}
 	return s.store.Count(s.zeroValue(), query)
 }
 
 func (s *store[T]) Find(query *badgerhold.Query) ([]T, error) {
+	if s.checked() {
+		var result []T
+		err := s.view(func(tx *badger.Txn) error {
+			var err error
+			result, err = s.TxFind(tx, query)
+			return err
+		})
+		return result, err
+	}
+
 	var result []T
 	// pointer to slice needs to badger limitation: panic: result argument must be a slice address
 	err := s.store.Find(&result, query)
+	for i := range result {
+		s.loaded(&result[i])
+	}
 	return result, err
 }
 
 func (s *store[T]) FindOne(query *badgerhold.Query) (T, error) {
+	if s.checked() {
+		var result T
+		err := s.view(func(tx *badger.Txn) error {
+			var err error
+			result, err = s.TxFindOne(tx, query)
+			return err
+		})
+		return result, err
+	}
+
 	var result T
 	err := s.store.FindOne(&result, query)
+	if err == nil {
+		s.loaded(&result)
+	}
 	return result, err
 }
 
-func (s *store[T]) ForEach(query *badgerhold.Query, fn any) error {
-	return s.store.ForEach(query, fn)
+func (s *store[T]) ForEach(query *badgerhold.Query, fn func(record *T) error) error {
+	if s.checked() {
+		return s.view(func(tx *badger.Txn) error {
+			return s.TxForEach(tx, query, fn)
+		})
+	}
+	return s.store.ForEach(query, func(record *T) error {
+		s.loaded(record)
+		return fn(record)
+	})
 }
 
 func (s *store[T]) Get(key any) (T, error) {
+	if err := s.ctxErr(); err != nil {
+		var zero T
+		return zero, err
+	}
+
 	var result T
 	err := s.store.Get(key, &result)
+	if err == nil && s.expired(&result) {
+		return s.zeroValue(), badgerhold.ErrNotFound
+	}
+	if err == nil {
+		s.loaded(&result)
+	}
 	return result, err
 }
 
 func (s *store[T]) TxCount(tx *badger.Txn, query *badgerhold.Query) (uint64, error) {
+	if s.checked() {
+		var count uint64
+		err := s.TxForEach(tx, query, func(*T) error {
+			count++
+			return nil
+		})
+		return count, err
+	}
 	return s.store.TxCount(tx, s.zeroValue(), query)
 }
 
 func (s *store[T]) TxFind(tx *badger.Txn, query *badgerhold.Query) ([]T, error) {
 	var result []T
+	if s.checked() {
+		err := s.TxForEach(tx, query, func(record *T) error {
+			result = append(result, *record)
+			return nil
+		})
+		return result, err
+	}
+
 	// pointer to slice needs to badger limitation: panic: result argument must be a slice address
 	err := s.store.TxFind(tx, &result, query)
+	for i := range result {
+		s.loaded(&result[i])
+	}
 	return result, err
 }
 
 func (s *store[T]) TxFindOne(tx *badger.Txn, query *badgerhold.Query) (T, error) {
 	var result T
+	if s.checked() {
+		found := false
+		err := s.TxForEach(tx, query, func(record *T) error {
+			result, found = *record, true
+			return errStopIteration
+		})
+		if err == errStopIteration {
+			err = nil
+		}
+		if err == nil && !found {
+			err = badgerhold.ErrNotFound
+		}
+		return result, err
+	}
+
 	err := s.store.TxFindOne(tx, &result, query)
+	if err == nil {
+		s.loaded(&result)
+	}
 	return result, err
 }
 
-func (s *store[T]) TxForEach(tx *badger.Txn, query *badgerhold.Query, fn any) error {
-	return s.store.TxForEach(tx, query, fn)
+func (s *store[T]) TxForEach(tx *badger.Txn, query *badgerhold.Query, fn func(record *T) error) error {
+	return s.forEach(tx, query, func(record *T) error {
+		if s.expired(record) {
+			return nil
+		}
+		return fn(record)
+	})
+}
+
+// forEach is TxForEach with the expired records, for writes
+func (s *store[T]) forEach(tx *badger.Txn, query *badgerhold.Query, fn func(record *T) error) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+	return s.store.TxForEach(tx, query, func(record *T) error {
+		if err := s.ctxErr(); err != nil {
+			return err
+		}
+		s.loaded(record)
+		return fn(record)
+	})
 }
 
 func (s *store[T]) TxGet(tx *badger.Txn, key any) (T, error) {
+	result, err := s.get(tx, key)
+	if err == nil && s.expired(&result) {
+		return s.zeroValue(), badgerhold.ErrNotFound
+	}
+	return result, err
+}
+
+// get is TxGet reading an expired record too, for writes
+func (s *store[T]) get(tx *badger.Txn, key any) (T, error) {
 	var result T
+	if err := s.ctxErr(); err != nil {
+		return result, err
+	}
+
 	err := s.store.TxGet(tx, key, &result)
+	if err == nil {
+		s.loaded(&result)
+	}
 	return result, err
 }
 
 func (s *store[T]) Insert(key any, data *T) error {
+	if !s.plain() {
+		return s.write(data, func(tx *badger.Txn) error {
+			return s.TxInsert(tx, key, data)
+		})
+	}
 	return s.store.Insert(key, data)
 }
 
 func (s *store[T]) TxInsert(tx *badger.Txn, key any, data *T) error {
-	return s.store.TxInsert(tx, key, data)
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+
+	written, restore, err := s.beforeWrite(tx, key, data, writeInsert)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.TxInsert(tx, key, data)
+	if err == nil {
+		err = written()
+	}
+	if err != nil {
+		restore()
+	}
+	return err
 }
 
 func (s *store[T]) TxUpdate(tx *badger.Txn, key any, data *T) error {
-	return s.store.TxUpdate(tx, key, data)
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+
+	written, restore, err := s.beforeWrite(tx, key, data, writeUpdate)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.TxUpdate(tx, key, data)
+	if err == nil {
+		err = written()
+	}
+	if err != nil {
+		restore()
+	}
+	return err
 }
 
 func (s *store[T]) TxUpdateMatching(tx *badger.Txn, query *badgerhold.Query, update func(record *T) error) error {
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+	if s.options.history || s.indexed() {
+		return s.updateEach(tx, query, update)
+	}
+
 	anyUpdate := func(record any) error {
-		return update(record.(*T))
+		if err := s.ctxErr(); err != nil {
+			return err
+		}
+		if s.plain() {
+			return update(record.(*T))
+		}
+
+		s.loaded(record.(*T))
+		stored := *record.(*T)
+		err := update(record.(*T))
+		if err != nil {
+			return err
+		}
+		return s.beforeMatchWrite(record.(*T), &stored)
 	}
 	return s.store.TxUpdateMatching(tx, s.zeroValue(), query, anyUpdate)
 }
 
 func (s *store[T]) TxUpsert(tx *badger.Txn, key any, data *T) error {
-	return s.store.TxUpsert(tx, key, data)
+	if err := s.ctxErr(); err != nil {
+		return err
+	}
+
+	written, restore, err := s.beforeWrite(tx, key, data, writeUpsert)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.TxUpsert(tx, key, data)
+	if err == nil {
+		err = written()
+	}
+	if err != nil {
+		restore()
+	}
+	return err
 }
 
 func (s *store[T]) Update(key any, data *T) error {
+	if !s.plain() {
+		return s.write(data, func(tx *badger.Txn) error {
+			return s.TxUpdate(tx, key, data)
+		})
+	}
 	return s.store.Update(key, data)
 }
 
 func (s *store[T]) UpdateMatching(query *badgerhold.Query, update func(record *T) error) error {
+	if !s.plain() {
+		return s.update(func(tx *badger.Txn) error {
+			return s.TxUpdateMatching(tx, query, update)
+		})
+	}
+
 	anyUpdate := func(record any) error {
 		return update(record.(*T))
 	}
@@ -159,6 +561,11 @@ func (s *store[T]) UpdateMatching(query *badgerhold.Query, update func(record *T
 }
 
 func (s *store[T]) Upsert(key any, data *T) error {
+	if !s.plain() {
+		return s.write(data, func(tx *badger.Txn) error {
+			return s.TxUpsert(tx, key, data)
+		})
+	}
 	return s.store.Upsert(key, data)
 }
 
@@ -170,7 +577,30 @@ func (s *store[T]) Close() error {
 	return s.store.Close()
 }
 
+// plain reports whether writes need nothing but badgerhold, so the methods opening their own transaction
+// can leave it to badgerhold
+func (s *store[T]) plain() bool {
+	return !s.checked() && s.versionIndex == nil && s.createdIndex == nil && s.updatedIndex == nil &&
+		s.deletedIndex == nil && !s.options.history && !s.indexed() && s.hooks == hooks{} && len(s.validators) == 0
+}
+
 func (s *store[T]) zeroValue() T {
 	var zero T
 	return zero
 }
+
+// typeName is the name badgerhold prefixes the keys of T with
+func typeName[T any]() string {
+	var zero T
+	if storer, ok := any(&zero).(badgerhold.Storer); ok {
+		return storer.Type()
+	}
+	if storer, ok := any(zero).(badgerhold.Storer); ok {
+		return storer.Type()
+	}
+
+	if tp := reflect.TypeOf(zero); tp != nil {
+		return tp.Name()
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"github.com/rlshukhov/generichold"
+	"testing"
+	"time"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type StampedItem struct {
+	ID      uint64 `badgerhold:"key"`
+	Name    string
+	Created time.Time `generichold:"createdAt"`
+	Updated time.Time `generichold:"updatedAt"`
+}
+
+func TestTimestamps(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := func() time.Time { return now }
+		tick := func() time.Time {
+			now = now.Add(time.Hour)
+			return now
+		}
+
+		store := generichold.OpenKeyed[uint64, StampedItem](bh, generichold.WithClock(clock))
+		created := now
+
+		item := StampedItem{Name: "first"}
+		ok(t, store.Insert(1, &item))
+		equals(t, created, item.Created)
+		equals(t, created, item.Updated)
+
+		tick()
+		// a zeroed createdAt is taken from the stored record
+		item = StampedItem{Name: "updated"}
+		ok(t, store.Update(1, &item))
+		equals(t, created, item.Created)
+		equals(t, now, item.Updated)
+
+		stored, err := store.Get(1)
+		ok(t, err)
+		equals(t, created, stored.Created.UTC())
+		equals(t, now, stored.Updated.UTC())
+
+		tick()
+		ok(t, store.Upsert(1, &StampedItem{Name: "upserted"}))
+		ok(t, store.Upsert(2, &StampedItem{Name: "inserted"}))
+		stored, err = store.Get(1)
+		ok(t, err)
+		equals(t, created, stored.Created.UTC())
+		equals(t, now, stored.Updated.UTC())
+		stored, err = store.Get(2)
+		ok(t, err)
+		equals(t, now, stored.Created.UTC())
+
+		tick()
+		ok(t, store.UpdateMatching(nil, func(record *StampedItem) error {
+			record.Created = time.Time{}
+			return nil
+		}))
+		stored, err = store.Get(1)
+		ok(t, err)
+		equals(t, created, stored.Created.UTC())
+		equals(t, now, stored.Updated.UTC())
+
+		// an inserted record keeps the createdAt it comes with
+		imported := StampedItem{Name: "imported", Created: created.Add(-time.Hour)}
+		ok(t, store.Insert(3, &imported))
+		equals(t, created.Add(-time.Hour), imported.Created)
+		equals(t, now, imported.Updated)
+	})
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Tx is a typed view of a store inside a single badger transaction
+type Tx[T any] interface {
+	FindAggregate(query *badgerhold.Query, groupBy ...string) ([]*badgerhold.AggregateResult, error)
+	Delete(key any) error
+	DeleteMatching(query *badgerhold.Query) error
+	Count(query *badgerhold.Query) (uint64, error)
+	Exists(key any) (bool, error)
+	Find(query *badgerhold.Query) ([]T, error)
+	FindOne(query *badgerhold.Query) (T, error)
+	FindPage(query *Query[T], cursor string, pageSize int) (Page[T], error)
+	FindWhere(query *Query[T]) ([]T, error)
+	ForEach(query *badgerhold.Query, fn func(record *T) error) error
+	Get(key any) (T, error)
+	Insert(key any, data *T) error
+	Update(key any, data *T) error
+	UpdateMatching(query *badgerhold.Query, update func(record *T) error) error
+	Upsert(key any, data *T) error
+	Txn() *badger.Txn
+}
+
+type typedTx[T any] struct {
+	store *store[T]
+	txn   *badger.Txn
+	// written is nil in read-only transactions
+	written *written
+}
+
+// written keeps the records passed to the writes of a transaction as they came, the writes change them
+// in place. They are put back before the transaction runs again on conflict, and when it is not committed,
+// so that a retry does not see the version a discarded attempt gave them.
+type written struct {
+	restores []func()
+}
+
+func keepWritten[T any](w *written, data *T) {
+	if w == nil || data == nil {
+		return
+	}
+	saved := *data
+	w.restores = append(w.restores, func() { *data = saved })
+}
+
+// restore puts the records back, the ones written more than once the way they came to the first write
+func (w *written) restore() {
+	for i := len(w.restores) - 1; i >= 0; i-- {
+		w.restores[i]()
+	}
+	w.restores = w.restores[:0]
+}
+
+// View runs fn in a read-only transaction
+func (s *store[T]) View(fn func(tx Tx[T]) error) error {
+	return s.view(func(txn *badger.Txn) error {
+		return fn(&typedTx[T]{store: s, txn: txn})
+	})
+}
+
+// UpdateTx runs fn in a read-write transaction, which is committed when fn returns nil and discarded
+// when it returns an error or panics. On badger.ErrConflict fn is run again in a new transaction,
+// as configured by WithConflictRetry. Records written through tx are put back the way they came
+// before fn runs again, and when the transaction is not committed.
+func (s *store[T]) UpdateTx(fn func(tx Tx[T]) error) error {
+	w := new(written)
+	err := s.update(func(txn *badger.Txn) error {
+		w.restore()
+		return fn(&typedTx[T]{store: s, txn: txn, written: w})
+	})
+	if err != nil {
+		w.restore()
+	}
+	return err
+}
+
+// Bind returns the typed view of the store inside tx, which must come from the same badgerhold.Store
+func (s *store[T]) Bind(tx *Txn) Tx[T] {
+	if tx.store != s.store {
+		panic("Txn is not of the badgerhold.Store the store was opened on")
+	}
+	return &typedTx[T]{store: s, txn: tx.txn, written: tx.written}
+}
+
+func (t *typedTx[T]) FindAggregate(query *badgerhold.Query, groupBy ...string) ([]*badgerhold.AggregateResult, error) {
+	return t.store.TxFindAggregate(t.txn, query, groupBy...)
+}
+
+func (t *typedTx[T]) Delete(key any) error {
+	return t.store.TxDelete(t.txn, key)
+}
+
+func (t *typedTx[T]) DeleteMatching(query *badgerhold.Query) error {
+	return t.store.TxDeleteMatching(t.txn, query)
+}
+
+func (t *typedTx[T]) Count(query *badgerhold.Query) (uint64, error) {
+	return t.store.TxCount(t.txn, query)
+}
+
+func (t *typedTx[T]) Exists(key any) (bool, error) {
+	return t.store.TxExists(t.txn, key)
+}
+
+func (t *typedTx[T]) Find(query *badgerhold.Query) ([]T, error) {
+	return t.store.TxFind(t.txn, query)
+}
+
+func (t *typedTx[T]) FindOne(query *badgerhold.Query) (T, error) {
+	return t.store.TxFindOne(t.txn, query)
+}
+
+func (t *typedTx[T]) FindPage(query *Query[T], cursor string, pageSize int) (Page[T], error) {
+	return t.store.TxFindPage(t.txn, query, cursor, pageSize)
+}
+
+func (t *typedTx[T]) FindWhere(query *Query[T]) ([]T, error) {
+	return t.store.TxFindWhere(t.txn, query)
+}
+
+func (t *typedTx[T]) ForEach(query *badgerhold.Query, fn func(record *T) error) error {
+	return t.store.TxForEach(t.txn, query, fn)
+}
+
+func (t *typedTx[T]) Get(key any) (T, error) {
+	return t.store.TxGet(t.txn, key)
+}
+
+func (t *typedTx[T]) Insert(key any, data *T) error {
+	keepWritten(t.written, data)
+	return t.store.TxInsert(t.txn, key, data)
+}
+
+func (t *typedTx[T]) Update(key any, data *T) error {
+	keepWritten(t.written, data)
+	return t.store.TxUpdate(t.txn, key, data)
+}
+
+func (t *typedTx[T]) UpdateMatching(query *badgerhold.Query, update func(record *T) error) error {
+	return t.store.TxUpdateMatching(t.txn, query, update)
+}
+
+func (t *typedTx[T]) Upsert(key any, data *T) error {
+	keepWritten(t.written, data)
+	return t.store.TxUpsert(t.txn, key, data)
+}
+
+func (t *typedTx[T]) Txn() *badger.Txn {
+	return t.txn
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func TestUpdateTxCommit(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+
+		ok(t, store.UpdateTx(func(tx generichold.Tx[ItemTest]) error {
+			for i := range testData {
+				err := tx.Insert(testData[i].Key, &testData[i])
+				if err != nil {
+					return err
+				}
+			}
+			return tx.Delete(testData[0].Key)
+		}))
+
+		ok(t, store.View(func(tx generichold.Tx[ItemTest]) error {
+			count, err := tx.Count(nil)
+			ok(t, err)
+			equals(t, uint64(len(testData)-1), count)
+			return nil
+		}))
+	})
+}
+
+func TestUpdateTxRollback(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh)
+
+		errRollback := errors.New("rollback")
+		err := store.UpdateTx(func(tx generichold.Tx[ItemTest]) error {
+			ok(t, tx.Insert(testData[0].Key, &testData[0]))
+			return errRollback
+		})
+		equals(t, errRollback, err)
+
+		func() {
+			defer func() {
+				assert(t, recover() != nil, "UpdateTx did not pass the panic through")
+			}()
+			_ = store.UpdateTx(func(tx generichold.Tx[ItemTest]) error {
+				ok(t, tx.Insert(testData[1].Key, &testData[1]))
+				panic("rollback")
+			})
+		}()
+
+		count, err := store.Count(nil)
+		ok(t, err)
+		equals(t, uint64(0), count)
+	})
+}
+
+func TestUpdateTxConflictRetry(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		var backoffs []int
+		store := generichold.OpenKeyed[int, ItemTest](bh, generichold.WithConflictRetry(2, func(attempt int) time.Duration {
+			backoffs = append(backoffs, attempt)
+			return 0
+		}))
+
+		ok(t, store.Insert(testData[0].Key, &testData[0]))
+
+		conflict := func(attempts *int, conflicts int) func(tx generichold.Tx[ItemTest]) error {
+			return func(tx generichold.Tx[ItemTest]) error {
+				*attempts++
+				record, err := tx.Get(testData[0].Key)
+				if err != nil {
+					return err
+				}
+
+				if *attempts <= conflicts {
+					// a concurrent write of the record read above makes the commit conflict
+					ok(t, store.Update(testData[0].Key, &record))
+				}
+
+				record.Name = "updated"
+				return tx.Update(testData[0].Key, &record)
+			}
+		}
+
+		attempts := 0
+		ok(t, store.UpdateTx(conflict(&attempts, 1)))
+		equals(t, 2, attempts)
+		equals(t, []int{1}, backoffs)
+
+		result, err := store.Get(testData[0].Key)
+		ok(t, err)
+		equals(t, "updated", result.Name)
+
+		attempts = 0
+		err = store.UpdateTx(conflict(&attempts, 3))
+		equals(t, badger.ErrConflict, err)
+		equals(t, 3, attempts)
+	})
+}
+
+func TestUpdateTxRetryRestoresRecords(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, VersionedItem](bh)
+		ok(t, store.Insert(1, &VersionedItem{Name: "one"}))
+		ok(t, store.Insert(2, &VersionedItem{Name: "two"}))
+
+		record, err := store.Get(1)
+		ok(t, err)
+		record.Name = "updated"
+
+		attempts := 0
+		ok(t, store.UpdateTx(func(tx generichold.Tx[VersionedItem]) error {
+			attempts++
+			other, err := tx.Get(uint64(2))
+			if err != nil {
+				return err
+			}
+			if attempts == 1 {
+				// a concurrent write of the record read above makes the commit conflict
+				ok(t, store.Update(2, &other))
+			}
+			// the update of the conflicting attempt bumped the version of record, the retry must not see it
+			return tx.Update(uint64(1), &record)
+		}))
+		equals(t, 2, attempts)
+		equals(t, uint64(2), record.Version)
+
+		stored, err := store.Get(1)
+		ok(t, err)
+		equals(t, "updated", stored.Name)
+		equals(t, uint64(2), stored.Version)
+	})
+}
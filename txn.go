@@ -0,0 +1,45 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// Txn is a read-write transaction shared by every store opened on the same badgerhold.Store,
+// use Bind on a store to get its typed view of the transaction.
+type Txn struct {
+	store   *badgerhold.Store
+	txn     *badger.Txn
+	written *written
+}
+
+// Transact runs fn in a single transaction across stores of different types, so writes through
+// every store bound to tx are committed atomically. It commits, discards, retries on conflict
+// and puts back the records written through the bound stores the same way UpdateTx does,
+// only WithConflictRetry of opts applies.
+func Transact(s *badgerhold.Store, fn func(tx *Txn) error, opts ...Option) error {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	w := new(written)
+	err := o.retry(func() error {
+		w.restore()
+		return s.Badger().Update(func(txn *badger.Txn) error {
+			return fn(&Txn{store: s, txn: txn, written: w})
+		})
+	})
+	if err != nil {
+		w.restore()
+	}
+	return err
+}
+
+func (t *Txn) Txn() *badger.Txn {
+	return t.txn
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type Order struct {
+	ID       uint64 `badgerhold:"key"`
+	Customer string
+}
+
+type OrderLine struct {
+	OrderID uint64 `badgerholdIndex:"OrderID"`
+	Product string
+}
+
+func TestTransact(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		orders := generichold.OpenKeyed[uint64, Order](bh)
+		lines := generichold.Open[OrderLine](bh)
+
+		order := &Order{Customer: "customer"}
+		ok(t, generichold.Transact(bh, func(tx *generichold.Txn) error {
+			err := orders.Bind(tx).Insert(badgerhold.NextSequence(), order)
+			if err != nil {
+				return err
+			}
+
+			for i, product := range []string{"apple", "pear"} {
+				err = lines.Bind(tx).Insert(i, &OrderLine{OrderID: order.ID, Product: product})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+
+		count, err := lines.Count(badgerhold.Where("OrderID").Eq(order.ID))
+		ok(t, err)
+		equals(t, uint64(2), count)
+
+		errRollback := errors.New("rollback")
+		err = generichold.Transact(bh, func(tx *generichold.Txn) error {
+			ok(t, orders.Bind(tx).Delete(order.ID))
+			ok(t, lines.Bind(tx).DeleteMatching(badgerhold.Where("OrderID").Eq(order.ID)))
+			return errRollback
+		})
+		equals(t, errRollback, err)
+
+		_, err = orders.Get(order.ID)
+		ok(t, err)
+		count, err = lines.Count(nil)
+		ok(t, err)
+		equals(t, uint64(2), count)
+	})
+}
+
+func TestBindOtherStore(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		testWrap(t, func(other *badgerhold.Store, t *testing.T) {
+			orders := generichold.Open[Order](other)
+
+			_ = generichold.Transact(bh, func(tx *generichold.Txn) error {
+				defer func() {
+					assert(t, recover() != nil, "Binding a store of another badgerhold.Store did not panic")
+				}()
+				orders.Bind(tx)
+				return nil
+			})
+		})
+	})
+}
+
+func TestTransactRetryRestoresRecords(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, VersionedItem](bh)
+		ok(t, store.Insert(1, &VersionedItem{Name: "one"}))
+		ok(t, store.Insert(2, &VersionedItem{Name: "two"}))
+
+		record, err := store.Get(1)
+		ok(t, err)
+
+		attempts := 0
+		ok(t, generichold.Transact(bh, func(tx *generichold.Txn) error {
+			attempts++
+			other, err := store.Bind(tx).Get(uint64(2))
+			if err != nil {
+				return err
+			}
+			if attempts == 1 {
+				ok(t, store.Update(2, &other))
+			}
+			return store.Bind(tx).Update(uint64(1), &record)
+		}))
+		equals(t, 2, attempts)
+		equals(t, uint64(2), record.Version)
+
+		// a transaction which is not committed leaves the record as it came
+		errRollback := errors.New("rollback")
+		err = generichold.Transact(bh, func(tx *generichold.Txn) error {
+			ok(t, store.Bind(tx).Update(uint64(1), &record))
+			return errRollback
+		})
+		equals(t, errRollback, err)
+		equals(t, uint64(2), record.Version)
+	})
+}
@@ -0,0 +1,343 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+// ErrUniqueViolation is returned when a write would give two records the same values for a group of fields
+// tagged `generichold:"unique=<group>"`, it matches badgerhold.ErrUniqueExists with errors.Is.
+// Fields of badgerhold's own `badgerhold:"unique"` tag still fail with a bare badgerhold.ErrUniqueExists.
+// Open indexes the records stored before a group was declared, and when two of them hold the same values
+// every method of the store returns their ErrUniqueViolation.
+type ErrUniqueViolation struct {
+	// Field is the name of the group
+	Field string
+	// Value is the value of the field of a single field group, the values of the fields in declaration order otherwise
+	Value any
+	// ExistingKey is the key of the record holding Value, decoded into the type of the key field of T,
+	// or else of the key of the write. It is left encoded when neither is known.
+	ExistingKey any
+}
+
+func (e *ErrUniqueViolation) Error() string {
+	return fmt.Sprintf("unique %s %v already exists under key %v", e.Field, e.Value, e.ExistingKey)
+}
+
+func (e *ErrUniqueViolation) Is(target error) bool {
+	return target == badgerhold.ErrUniqueExists
+}
+
+// uniqueGroup is a group of fields tagged `generichold:"unique=<name>"`, in declaration order
+type uniqueGroup struct {
+	name   string
+	fields [][]int
+}
+
+func uniqueGroupsOf(tp reflect.Type) []uniqueGroup {
+	var groups []uniqueGroup
+	for i := 0; i < tp.NumField(); i++ {
+		for _, tag := range strings.Split(tp.Field(i).Tag.Get("generichold"), ",") {
+			name, ok := strings.CutPrefix(tag, "unique=")
+			if !ok {
+				continue
+			}
+
+			j := 0
+			for j < len(groups) && groups[j].name != name {
+				j++
+			}
+			if j == len(groups) {
+				groups = append(groups, uniqueGroup{name: name})
+			}
+			groups[j].fields = append(groups[j].fields, tp.Field(i).Index)
+		}
+	}
+	return groups
+}
+
+// GetByUnique reads the record whose field holds value from a unique index: the one of a group of that
+// single field tagged `generichold:"unique=<group>"`, or badgerhold's own of a field tagged `badgerhold:"unique"`.
+// An expired record fails with badgerhold.ErrNotFound, as Get does.
+func GetByUnique[T, V any](s Store[T], field Field[T, V], value V) (T, error) {
+	var result T
+	err := s.View(func(tx Tx[T]) error {
+		var err error
+		result, err = TxGetByUnique(s, tx.Txn(), field, value)
+		return err
+	})
+	return result, err
+}
+
+func TxGetByUnique[T, V any](s Store[T], tx *badger.Txn, field Field[T, V], value V) (T, error) {
+	if u, ok := s.(interface {
+		getByUnique(tx *badger.Txn, field string, value any) (T, error)
+	}); ok {
+		return u.getByUnique(tx, string(field), value)
+	}
+	return s.TxFindOne(tx, badgerhold.Where(string(field)).Eq(value))
+}
+
+func (s *store[T]) getByUnique(tx *badger.Txn, field string, value any) (T, error) {
+	var result T
+	if err := s.ctxErr(); err != nil {
+		return result, err
+	}
+
+	tp := reflect.TypeOf(result)
+	encoded, err := s.options.encode(value)
+	if err != nil {
+		return result, err
+	}
+
+	var key []byte
+	for _, group := range s.uniqueGroups {
+		if len(group.fields) == 1 && tp.FieldByIndex(group.fields[0]).Name == field {
+			// a read-only database may hold records written before the group was declared
+			built, err := exists(tx, s.uniqueBuilt(group))
+			if err != nil || !built {
+				if err == nil {
+					result, err = s.TxFindOne(tx, badgerhold.Where(field).Eq(value))
+				}
+				return result, err
+			}
+			key, err = s.uniqueEntry(tx, s.uniqueKey(group, [][]byte{encoded}))
+			return s.getEncoded(tx, key, err)
+		}
+	}
+
+	indexed, ok := indexField(tp, field)
+	if !ok || indexed.Tag.Get("badgerhold") != "unique" {
+		return result, fmt.Errorf("field %s of %s has no unique index", field, tp.Name())
+	}
+
+	item, err := tx.Get([]byte("_bhIndex:" + typeName[T]() + ":" + field + ":" + string(encoded)))
+	if err == nil {
+		var keys badgerhold.KeyList
+		err = item.Value(func(value []byte) error {
+			return s.options.decode(value, &keys)
+		})
+		if err == nil && len(keys) != 0 {
+			key = keys[0][len(s.prefix):]
+		}
+	}
+	return s.getEncoded(tx, key, err)
+}
+
+// getEncoded reads the record under an encoded key, err is the error of looking the key up
+func (s *store[T]) getEncoded(tx *badger.Txn, key []byte, err error) (T, error) {
+	var result T
+	if err == badger.ErrKeyNotFound || (err == nil && key == nil) {
+		return result, badgerhold.ErrNotFound
+	}
+	if err != nil {
+		return result, err
+	}
+
+	item, err := tx.Get(append(s.prefix[:len(s.prefix):len(s.prefix)], key...))
+	if err == badger.ErrKeyNotFound {
+		return result, badgerhold.ErrNotFound
+	}
+	if err != nil {
+		return result, err
+	}
+	err = item.Value(func(value []byte) error {
+		record, err := s.decodeRecord(key, value)
+		if err == nil {
+			result = *record
+		}
+		return err
+	})
+	if err == nil && s.expired(&result) {
+		return s.zeroValue(), badgerhold.ErrNotFound
+	}
+	return result, err
+}
+
+// checkUnique fails with ErrUniqueViolation when data, to be written under key, takes the values
+// of a unique group from another record
+func (s *store[T]) checkUnique(tx *badger.Txn, key any, data *T, inserted bool) error {
+	if len(s.uniqueGroups) == 0 {
+		return nil
+	}
+	if key == badgerhold.NextSequence() && s.keyIndex == nil {
+		// the generated key would be unknown to the index
+		return ErrNoKeyField
+	}
+
+	var own []byte
+	if !inserted {
+		var err error
+		own, err = s.options.encode(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, group := range s.uniqueGroups {
+		values, err := s.uniqueValues(group, data)
+		if err != nil {
+			return err
+		}
+
+		existing, err := s.uniqueEntry(tx, s.uniqueKey(group, values))
+		if err == badger.ErrKeyNotFound || (err == nil && bytes.Equal(existing, own)) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return &ErrUniqueViolation{Field: group.name, Value: s.uniqueValue(group, data), ExistingKey: s.existingKey(existing, key)}
+	}
+	return nil
+}
+
+// buildUnique points group at the stored records, unless it is built already. It fails with ErrUniqueViolation
+// when two records hold the same values, written before the group was declared.
+func (s *store[T]) buildUnique(group uniqueGroup) error {
+	return s.buildIndex(s.uniqueBuilt(group), s.uniquePrefix(group), func(tx *badger.Txn, key []byte, record *T) error {
+		values, err := s.uniqueValues(group, record)
+		if err != nil {
+			return err
+		}
+		unique := s.uniqueKey(group, values)
+		existing, err := s.uniqueEntry(tx, unique)
+		if err == nil {
+			return &ErrUniqueViolation{Field: group.name, Value: s.uniqueValue(group, record),
+				ExistingKey: s.existingKey(existing, badgerhold.NextSequence())}
+		}
+		if err != badger.ErrKeyNotFound {
+			return err
+		}
+		return tx.Set(unique, key)
+	})
+}
+
+// indexUnique points the unique groups of data at key once data is written, replacing those of stored
+func (s *store[T]) indexUnique(tx *badger.Txn, key any, data, stored *T) error {
+	if len(s.uniqueGroups) == 0 {
+		return nil
+	}
+	if key == badgerhold.NextSequence() {
+		key = s.recordKey(data)
+	}
+
+	err := s.dropUnique(tx, stored)
+	if err != nil {
+		return err
+	}
+	encoded, err := s.options.encode(key)
+	if err != nil {
+		return err
+	}
+	for _, group := range s.uniqueGroups {
+		values, err := s.uniqueValues(group, data)
+		if err != nil {
+			return err
+		}
+		err = tx.Set(s.uniqueKey(group, values), encoded)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropUnique removes the unique groups of stored, a record deleted or about to be overwritten
+func (s *store[T]) dropUnique(tx *badger.Txn, stored *T) error {
+	if len(s.uniqueGroups) == 0 || stored == nil {
+		return nil
+	}
+
+	for _, group := range s.uniqueGroups {
+		values, err := s.uniqueValues(group, stored)
+		if err != nil {
+			return err
+		}
+		err = tx.Delete(s.uniqueKey(group, values))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *store[T]) uniqueValues(group uniqueGroup, record *T) ([][]byte, error) {
+	values := make([][]byte, len(group.fields))
+	for i, field := range group.fields {
+		var err error
+		values[i], err = s.options.encode(reflect.ValueOf(record).Elem().FieldByIndex(field).Interface())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (s *store[T]) uniqueValue(group uniqueGroup, record *T) any {
+	values := make([]any, len(group.fields))
+	for i, field := range group.fields {
+		values[i] = reflect.ValueOf(record).Elem().FieldByIndex(field).Interface()
+	}
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+// uniqueKey returns the badger key of the values of a group, each value goes after its length
+// so that different values never join into the same key
+func (s *store[T]) uniqueKey(group uniqueGroup, values [][]byte) []byte {
+	key := s.uniquePrefix(group)
+	for _, value := range values {
+		key = appendPart(key, value)
+	}
+	return key
+}
+
+func (s *store[T]) uniquePrefix(group uniqueGroup) []byte {
+	return []byte("gh_unique:" + typeName[T]() + ":" + group.name + ":")
+}
+
+// uniqueBuilt is the badger key set once every stored record is in group
+func (s *store[T]) uniqueBuilt(group uniqueGroup) []byte {
+	return []byte("gh_uniqued:" + typeName[T]() + ":" + group.name)
+}
+
+// uniqueEntry returns the encoded key of the record a unique key points at
+func (s *store[T]) uniqueEntry(tx *badger.Txn, unique []byte) ([]byte, error) {
+	item, err := tx.Get(unique)
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+// existingKey decodes the key of another record for ErrUniqueViolation, like is the key of the write,
+// or badgerhold.NextSequence when there is none
+func (s *store[T]) existingKey(encoded []byte, like any) any {
+	var tp reflect.Type
+	if s.keyIndex != nil {
+		tp = reflect.TypeOf(s.zeroValue()).FieldByIndex(s.keyIndex).Type
+	} else if like != badgerhold.NextSequence() {
+		tp = reflect.TypeOf(like)
+	}
+	if tp == nil {
+		return encoded
+	}
+
+	key := reflect.New(tp)
+	if err := s.options.decode(encoded, key.Interface()); err != nil {
+		return encoded
+	}
+	return key.Elem().Interface()
+}
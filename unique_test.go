@@ -0,0 +1,141 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"testing"
+	"time"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type Account struct {
+	ID       uint64 `badgerhold:"key"`
+	TenantID uint64 `generichold:"unique=tenant_email"`
+	Email    string `generichold:"unique=tenant_email"`
+	Login    string `generichold:"unique=login"`
+	Phone    string `badgerhold:"unique"`
+}
+
+func TestCompositeUnique(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, Account](bh)
+		ok(t, store.Insert(1, &Account{TenantID: 1, Email: "a@example.com", Login: "a", Phone: "1"}))
+		// the same email in another tenant
+		ok(t, store.Insert(2, &Account{TenantID: 2, Email: "a@example.com", Login: "b", Phone: "2"}))
+
+		err := store.Insert(3, &Account{TenantID: 1, Email: "a@example.com", Login: "c", Phone: "3"})
+		assert(t, errors.Is(err, badgerhold.ErrUniqueExists), "duplicate did not match ErrUniqueExists: %v", err)
+		var violation *generichold.ErrUniqueViolation
+		assert(t, errors.As(err, &violation), "duplicate is not an ErrUniqueViolation: %v", err)
+		equals(t, generichold.ErrUniqueViolation{Field: "tenant_email", Value: []any{uint64(1), "a@example.com"}, ExistingKey: uint64(1)}, *violation)
+
+		err = store.Update(2, &Account{TenantID: 2, Email: "b@example.com", Login: "a", Phone: "2"})
+		assert(t, errors.As(err, &violation), "duplicate update is not an ErrUniqueViolation: %v", err)
+		equals(t, generichold.ErrUniqueViolation{Field: "login", Value: "a", ExistingKey: uint64(1)}, *violation)
+
+		// a record keeps its own values, and frees the ones it changes
+		ok(t, store.Update(1, &Account{TenantID: 1, Email: "a@example.com", Login: "renamed", Phone: "1"}))
+		ok(t, store.Upsert(3, &Account{TenantID: 3, Email: "c@example.com", Login: "a", Phone: "3"}))
+
+		err = store.UpdateMatching(badgerhold.Where("TenantID").Ge(uint64(2)), func(record *Account) error {
+			record.Login = "same"
+			return nil
+		})
+		assert(t, errors.As(err, &violation), "duplicate UpdateMatching is not an ErrUniqueViolation: %v", err)
+		equals(t, "login", violation.Field)
+
+		ok(t, store.Delete(1))
+		ok(t, store.Insert(4, &Account{TenantID: 1, Email: "a@example.com", Login: "renamed", Phone: "4"}))
+
+		accounts := generichold.Open[Account](bh)
+		login := generichold.Field[Account, string]("Login")
+		record, err := generichold.GetByUnique(accounts, login, "renamed")
+		ok(t, err)
+		equals(t, uint64(4), record.ID)
+		_, err = generichold.GetByUnique(accounts, login, "missing")
+		equals(t, badgerhold.ErrNotFound, err)
+
+		record, err = generichold.GetByUnique(accounts, generichold.Field[Account, string]("Phone"), "3")
+		ok(t, err)
+		equals(t, uint64(3), record.ID)
+
+		_, err = generichold.GetByUnique(accounts, generichold.Field[Account, string]("Email"), "a@example.com")
+		assert(t, err != nil, "GetByUnique read a field of a composite group")
+	})
+}
+
+func TestUniqueBuiltOnOpen(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		func() {
+			type Member struct {
+				ID    uint64 `badgerhold:"key"`
+				Login string
+			}
+			plain := generichold.OpenKeyed[uint64, Member](bh)
+			ok(t, plain.Insert(1, &Member{Login: "a"}))
+			ok(t, plain.Insert(2, &Member{Login: "b"}))
+
+			type Guest struct {
+				ID    uint64 `badgerhold:"key"`
+				Login string
+			}
+			guests := generichold.OpenKeyed[uint64, Guest](bh)
+			ok(t, guests.Insert(1, &Guest{Login: "same"}))
+			ok(t, guests.Insert(2, &Guest{Login: "same"}))
+		}()
+
+		// the records stored before the group was declared are in it
+		type Member struct {
+			ID    uint64 `badgerhold:"key"`
+			Login string `generichold:"unique=login"`
+		}
+		store := generichold.OpenKeyed[uint64, Member](bh)
+		err := store.Insert(3, &Member{Login: "a"})
+		var violation *generichold.ErrUniqueViolation
+		assert(t, errors.As(err, &violation), "a duplicate of a record stored before the group was written: %v", err)
+		equals(t, generichold.ErrUniqueViolation{Field: "login", Value: "a", ExistingKey: uint64(1)}, *violation)
+		record, err := generichold.GetByUnique(generichold.Open[Member](bh), generichold.Field[Member, string]("Login"), "b")
+		ok(t, err)
+		equals(t, uint64(2), record.ID)
+
+		// duplicates stored before the group was declared fail the store
+		type Guest struct {
+			ID    uint64 `badgerhold:"key"`
+			Login string `generichold:"unique=login"`
+		}
+		_, err = generichold.OpenKeyed[uint64, Guest](bh).Get(1)
+		assert(t, errors.As(err, &violation), "duplicates stored before the group were accepted: %v", err)
+		equals(t, "login", violation.Field)
+		equals(t, "same", violation.Value)
+	})
+}
+
+type ExpiringAccount struct {
+	ID      uint64    `badgerhold:"key"`
+	Login   string    `generichold:"unique=login"`
+	Phone   string    `badgerhold:"unique"`
+	Expires time.Time `generichold:"expiresAt"`
+}
+
+func TestGetByUniqueExpired(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := generichold.Open[ExpiringAccount](bh, generichold.WithClock(func() time.Time { return now }))
+		ok(t, store.InsertWithTTL(uint64(1), &ExpiringAccount{Login: "a", Phone: "1"}, time.Minute))
+
+		record, err := generichold.GetByUnique(store, generichold.Field[ExpiringAccount, string]("Login"), "a")
+		ok(t, err)
+		equals(t, uint64(1), record.ID)
+
+		now = now.Add(time.Minute)
+		_, err = generichold.GetByUnique(store, generichold.Field[ExpiringAccount, string]("Login"), "a")
+		equals(t, badgerhold.ErrNotFound, err)
+		_, err = generichold.GetByUnique(store, generichold.Field[ExpiringAccount, string]("Phone"), "1")
+		equals(t, badgerhold.ErrNotFound, err)
+	})
+}
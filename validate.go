@@ -0,0 +1,112 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validator checks records of T before they are written, see WithValidator.
+type Validator[T any] interface {
+	Validate(record *T) error
+}
+
+// ValidatorFunc is a func used as a Validator.
+type ValidatorFunc[T any] func(record *T) error
+
+func (f ValidatorFunc[T]) Validate(record *T) error {
+	return f(record)
+}
+
+// ValidationError lists the fields of a record which failed validation, nothing of the write was stored.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// FieldError is a single failed check of a ValidationError, Field is empty for checks of the whole record.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		if field.Field == "" {
+			messages[i] = field.Err.Error()
+		} else {
+			messages[i] = field.Field + ": " + field.Err.Error()
+		}
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Unwrap lets errors.Is and errors.As look into the field errors
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i := range e.Fields {
+		errs[i] = e.Fields[i].Err
+	}
+	return errs
+}
+
+// WithValidator adds v to the checks Insert, Update, Upsert and UpdateMatching run on every record
+// before writing it, after its hooks. A *ValidationError returned by v has its fields merged with those
+// of the other validators, any other error is listed as a check of the whole record. T implementing
+// Validate() error on T or *T is validated the same way without the option.
+// Every method of the store of T returns an error when v validates a different type.
+func WithValidator[T any](v Validator[T]) Option {
+	return func(o *options) {
+		o.validators = append(o.validators, v)
+	}
+}
+
+type validateMethod[T any] struct{}
+
+func (validateMethod[T]) Validate(record *T) error {
+	return any(record).(interface{ Validate() error }).Validate()
+}
+
+func validatorsOf[T any](o options) ([]Validator[T], error) {
+	var validators []Validator[T]
+	if _, ok := any(new(T)).(interface{ Validate() error }); ok {
+		validators = append(validators, validateMethod[T]{})
+	}
+
+	for _, v := range o.validators {
+		validator, ok := v.(Validator[T])
+		if !ok {
+			var zero T
+			return nil, fmt.Errorf("validator %T does not validate %T", v, zero)
+		}
+		validators = append(validators, validator)
+	}
+	return validators, nil
+}
+
+// validate runs every validator of the store on record
+func (s *store[T]) validate(record *T) error {
+	var result ValidationError
+	for _, validator := range s.validators {
+		err := validator.Validate(record)
+		if err == nil {
+			continue
+		}
+
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			result.Fields = append(result.Fields, validationErr.Fields...)
+		} else {
+			result.Fields = append(result.Fields, FieldError{Err: err})
+		}
+	}
+
+	if len(result.Fields) != 0 {
+		return &result
+	}
+	return nil
+}
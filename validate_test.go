@@ -0,0 +1,111 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+var errRequired = errors.New("is required")
+
+type ValidatedItem struct {
+	ID       uint64 `badgerhold:"key"`
+	Name     string
+	Category string
+}
+
+func (i *ValidatedItem) Validate() error {
+	if i.Name == "" {
+		return &generichold.ValidationError{Fields: []generichold.FieldError{{Field: "Name", Err: errRequired}}}
+	}
+	return nil
+}
+
+func TestValidation(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		errBlocked := errors.New("blocked category")
+		store := generichold.OpenKeyed[uint64, ValidatedItem](bh,
+			generichold.WithValidator[ValidatedItem](generichold.ValidatorFunc[ValidatedItem](func(record *ValidatedItem) error {
+				if record.Category == "" {
+					return &generichold.ValidationError{Fields: []generichold.FieldError{{Field: "Category", Err: errRequired}}}
+				}
+				return nil
+			})),
+			generichold.WithValidator[ValidatedItem](generichold.ValidatorFunc[ValidatedItem](func(record *ValidatedItem) error {
+				if record.Category == "blocked" {
+					return errBlocked
+				}
+				return nil
+			})))
+
+		err := store.Insert(1, &ValidatedItem{})
+		var validationErr *generichold.ValidationError
+		assert(t, errors.As(err, &validationErr), "Insert did not return a ValidationError: %v", err)
+		equals(t, []generichold.FieldError{{Field: "Name", Err: errRequired}, {Field: "Category", Err: errRequired}},
+			validationErr.Fields)
+		equals(t, "validation failed: Name: is required; Category: is required", err.Error())
+
+		err = store.Insert(1, &ValidatedItem{Name: "first", Category: "blocked"})
+		assert(t, errors.Is(err, errBlocked), "the error of the validator is not kept: %v", err)
+
+		ok(t, store.Insert(1, &ValidatedItem{Name: "first", Category: "blue"}))
+
+		err = store.Update(1, &ValidatedItem{Category: "blue"})
+		assert(t, errors.Is(err, errRequired), "Update was not validated: %v", err)
+		err = store.Upsert(2, &ValidatedItem{Name: "second"})
+		assert(t, errors.Is(err, errRequired), "Upsert was not validated: %v", err)
+
+		err = store.UpdateMatching(nil, func(record *ValidatedItem) error {
+			record.Name = ""
+			return nil
+		})
+		assert(t, errors.Is(err, errRequired), "UpdateMatching was not validated: %v", err)
+
+		items, err := store.Find(nil)
+		ok(t, err)
+		equals(t, []ValidatedItem{{ID: 1, Name: "first", Category: "blue"}}, items)
+	})
+}
+
+func TestValidatorOfAnotherType(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[ItemTest](bh, generichold.WithValidator[ValidatedItem](generichold.ValidatorFunc[ValidatedItem](
+			func(*ValidatedItem) error { return nil })))
+
+		// every method fails with the error of Open, and nothing is written
+		assert(t, store.Insert(1, &ItemTest{Key: 1}) != nil, "Open accepted a validator of another type")
+		_, err := store.Find(nil)
+		assert(t, err != nil, "Find ignored the validator of another type")
+		_, err = store.Count(nil)
+		assert(t, err != nil, "Count ignored the validator of another type")
+		_, err = store.Get(1)
+		assert(t, err != nil && err != badgerhold.ErrNotFound, "Get ignored the validator of another type")
+		count, err := generichold.Open[ItemTest](bh).Count(nil)
+		ok(t, err)
+		equals(t, uint64(0), count)
+	})
+}
+
+type BadlyManagedItem struct {
+	ID      int
+	Version int `generichold:"version"`
+}
+
+func TestManagedFieldOfAnotherType(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.Open[BadlyManagedItem](bh)
+		assert(t, store.Insert(1, &BadlyManagedItem{ID: 1}) != nil, "Open accepted a version field of type int")
+		assert(t, store.ForEach(nil, func(*BadlyManagedItem) error { return nil }) != nil,
+			"ForEach ignored the version field of type int")
+
+		store = generichold.Open[BadlyManagedItem](bh, generichold.WithOnExpire(func(*ItemTest) {}))
+		_, err := store.Find(nil)
+		assert(t, err != nil, "Open accepted an OnExpire of another type")
+	})
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrVersionConflict is matched by errors.Is on the *VersionConflictError of a write carrying a stale version
+var ErrVersionConflict = errors.New("record version conflict")
+
+// VersionConflictError is returned when a record is written with a version field other than the stored one,
+// which means it was changed since it was read.
+//
+// A uint64 field tagged `generichold:"version"` is set to 1 on Insert and incremented by every
+// Update, Upsert and UpdateMatching, which only write records carrying the stored version.
+type VersionConflictError struct {
+	Key     any
+	Version uint64
+	Stored  uint64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("record %v has version %d, not the stored version %d", e.Key, e.Version, e.Stored)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// version returns the version field of record, T must have one
+func (s *store[T]) version(record *T) reflect.Value {
+	return reflect.ValueOf(record).Elem().FieldByIndex(s.versionIndex)
+}
+
+// nextVersion checks data carries the version of stored and sets the version data is written with,
+// stored is nil when data is inserted
+func (s *store[T]) nextVersion(key any, data, stored *T) error {
+	if s.versionIndex == nil {
+		return nil
+	}
+
+	next := uint64(1)
+	if stored != nil {
+		version, current := s.version(data).Uint(), s.version(stored).Uint()
+		if version != current {
+			return &VersionConflictError{Key: key, Version: version, Stored: current}
+		}
+		next = current + 1
+	}
+
+	s.version(data).SetUint(next)
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"errors"
+	"github.com/rlshukhov/generichold"
+	"testing"
+
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type VersionedItem struct {
+	ID      uint64 `badgerhold:"key"`
+	Name    string
+	Version uint64 `generichold:"version"`
+}
+
+func TestVersionField(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, VersionedItem](bh)
+
+		item := VersionedItem{Name: "first"}
+		ok(t, store.Insert(1, &item))
+		equals(t, uint64(1), item.Version)
+
+		first, err := store.Get(1)
+		ok(t, err)
+		second := first
+
+		first.Name = "updated"
+		ok(t, store.Update(1, &first))
+		equals(t, uint64(2), first.Version)
+
+		// second was read before the update
+		second.Name = "lost"
+		err = store.Update(1, &second)
+		assert(t, errors.Is(err, generichold.ErrVersionConflict), "stale update was not rejected: %v", err)
+		var conflict *generichold.VersionConflictError
+		assert(t, errors.As(err, &conflict), "conflict is not a VersionConflictError")
+		equals(t, uint64(1), conflict.Version)
+		equals(t, uint64(2), conflict.Stored)
+		equals(t, uint64(1), second.Version)
+
+		err = store.Upsert(1, &second)
+		assert(t, errors.Is(err, generichold.ErrVersionConflict), "stale upsert was not rejected: %v", err)
+		ok(t, store.Upsert(1, &first))
+		equals(t, uint64(3), first.Version)
+
+		upserted := VersionedItem{Name: "upserted", Version: 7}
+		ok(t, store.Upsert(2, &upserted))
+		equals(t, uint64(1), upserted.Version)
+
+		ok(t, store.UpdateMatching(nil, func(record *VersionedItem) error {
+			record.Name = "matched"
+			return nil
+		}))
+		record, err := store.Get(1)
+		ok(t, err)
+		equals(t, uint64(4), record.Version)
+		record, err = store.Get(2)
+		ok(t, err)
+		equals(t, uint64(2), record.Version)
+
+		modified, err := store.Modify(1, func(record *VersionedItem) error {
+			record.Name = "modified"
+			return nil
+		})
+		ok(t, err)
+		equals(t, uint64(5), modified.Version)
+
+		swapped, err := store.CompareAndSwap(1, &modified, &VersionedItem{Name: "swapped"})
+		ok(t, err)
+		assert(t, swapped, "CompareAndSwap did not swap the current record")
+		record, err = store.Get(1)
+		ok(t, err)
+		equals(t, uint64(6), record.Version)
+	})
+}
+
+func TestVersionConflictRetried(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		other := generichold.OpenKeyed[uint64, VersionedItem](bh)
+		ok(t, other.Insert(1, &VersionedItem{Name: "first"}))
+		stale, err := other.Get(1)
+		ok(t, err)
+
+		// another writer commits while the update runs, so its commit conflicts and it runs again
+		interleaved := false
+		store := generichold.OpenKeyed[uint64, VersionedItem](bh, generichold.WithValidator[VersionedItem](
+			generichold.ValidatorFunc[VersionedItem](func(*VersionedItem) error {
+				if interleaved {
+					return nil
+				}
+				interleaved = true
+				theirs, err := other.Get(1)
+				if err != nil {
+					return err
+				}
+				theirs.Name = "theirs"
+				return other.Update(1, &theirs)
+			})))
+
+		stale.Name = "mine"
+		err = store.Update(1, &stale)
+		assert(t, errors.Is(err, generichold.ErrVersionConflict), "stale update was not rejected on retry: %v", err)
+		equals(t, uint64(1), stale.Version)
+
+		record, err := store.Get(1)
+		ok(t, err)
+		equals(t, VersionedItem{ID: 1, Name: "theirs", Version: 2}, record)
+	})
+}
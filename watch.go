@@ -0,0 +1,215 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/pb"
+)
+
+// ErrVersionsNotKept is returned by Watch and the AsOf views when badger is set to keep a single
+// version of a key, so past versions are gone by the time they are read
+var ErrVersionsNotKept = errors.New("badger does not keep the previous versions of keys, set NumVersionsToKeep above 1")
+
+type ChangeType int
+
+const (
+	ChangeInsert ChangeType = iota + 1
+	ChangeUpdate
+	ChangeDelete
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case ChangeInsert:
+		return "insert"
+	case ChangeUpdate:
+		return "update"
+	case ChangeDelete:
+		return "delete"
+	}
+	return fmt.Sprintf("ChangeType(%d)", int(c))
+}
+
+// Change is a single write to a watched store.
+// Old is nil on insert and New is nil on delete.
+type Change[T any] struct {
+	Type ChangeType
+	// Key is the encoded key, records with a key field also carry it decoded
+	Key []byte
+	Old *T
+	New *T
+	// Err is set on the last change sent when watching failed, the change carries nothing else
+	Err error
+}
+
+// Watch emits every committed write to records of T matching query until ctx is done, a nil query matches
+// every record. A change is emitted when either its old or its new value matches, so records moving out of
+// the query are reported too. Old values are read from the previous badger version of the key, so Watch
+// returns ErrVersionsNotKept unless badger keeps more than one version; a version may still be discarded
+// when the key is written again before its change is read, and the change is then reported as an insert.
+// When watching fails the error is sent as the Err of a last change before the channel is closed.
+func (s *store[T]) Watch(ctx context.Context, query *Query[T]) (<-chan Change[T], error) {
+	if err := s.ctxErr(); err != nil {
+		return nil, err
+	}
+	if s.Badger().Opts().NumVersionsToKeep < 2 {
+		return nil, ErrVersionsNotKept
+	}
+
+	changes := make(chan Change[T])
+	ready := make(chan struct{})
+	done := make(chan error, 1)
+	readyKey := []byte(fmt.Sprintf("gh_watch:%p", changes))
+
+	ctx, cancel := context.WithCancel(ctx)
+	var readyOnce sync.Once
+	go func() {
+		defer cancel()
+		defer close(changes)
+
+		matches := []pb.Match{{Prefix: s.prefix}, {Prefix: readyKey}}
+		err := s.Badger().Subscribe(ctx, func(kvs *badger.KVList) error {
+			for _, kv := range kvs.Kv {
+				if bytes.Equal(kv.Key, readyKey) {
+					readyOnce.Do(func() { close(ready) })
+					continue
+				}
+
+				change, ok, err := s.change(kv, query)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		}, matches)
+
+		select {
+		case <-ready:
+			if err != nil && ctx.Err() == nil {
+				select {
+				case changes <- Change[T]{Err: err}:
+				case <-ctx.Done():
+				}
+			}
+		default:
+			if err == nil {
+				err = badger.ErrDBClosed
+			}
+			done <- err
+		}
+	}()
+
+	// the subscription is registered asynchronously, so Watch deletes the ready key, which leaves
+	// nothing in the store, until the subscription sees it, waiting longer each time
+	for wait := time.Millisecond; ; wait = min(2*wait, 100*time.Millisecond) {
+		err := s.Badger().Update(func(tx *badger.Txn) error {
+			return tx.Delete(readyKey)
+		})
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		select {
+		case <-ready:
+			return changes, nil
+		case err := <-done:
+			return nil, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (s *store[T]) change(kv *pb.KV, query *Query[T]) (Change[T], bool, error) {
+	change := Change[T]{Key: kv.Key[len(s.prefix):]}
+
+	var err error
+	if len(kv.Value) != 0 {
+		change.New, err = s.decodeRecord(change.Key, kv.Value)
+		if err != nil {
+			return change, false, err
+		}
+	}
+
+	change.Old, err = s.previous(kv.Key, kv.Version)
+	if err != nil {
+		return change, false, err
+	}
+
+	switch {
+	case change.New == nil:
+		change.Type = ChangeDelete
+	case change.Old == nil:
+		change.Type = ChangeInsert
+	default:
+		change.Type = ChangeUpdate
+	}
+
+	if query == nil || (change.New == nil && change.Old == nil) {
+		return change, true, nil
+	}
+
+	for _, record := range []*T{change.New, change.Old} {
+		if record == nil {
+			continue
+		}
+		ok, err := query.matches(&row[T]{key: change.Key, record: record, decode: s.options.decode})
+		if err != nil || ok {
+			return change, ok, err
+		}
+	}
+
+	return change, false, nil
+}
+
+// previous decodes the newest version of key older than version, nil if there is none
+func (s *store[T]) previous(key []byte, version uint64) (*T, error) {
+	var record *T
+	err := s.Badger().View(func(tx *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.AllVersions = true
+		opts.Prefix = key
+		iter := tx.NewIterator(opts)
+		defer iter.Close()
+
+		for iter.Seek(key); iter.ValidForPrefix(key); iter.Next() {
+			item := iter.Item()
+			if !bytes.Equal(item.Key(), key) {
+				return nil
+			}
+			if item.Version() >= version {
+				continue
+			}
+			if item.IsDeletedOrExpired() {
+				return nil
+			}
+
+			return item.Value(func(value []byte) error {
+				var err error
+				record, err = s.decodeRecord(key[len(s.prefix):], value)
+				return err
+			})
+		}
+		return nil
+	})
+	return record, err
+}
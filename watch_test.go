@@ -0,0 +1,109 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold_test
+
+import (
+	"context"
+	"github.com/rlshukhov/generichold"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+func nextChange[T any](t *testing.T, changes <-chan generichold.Change[T]) generichold.Change[T] {
+	select {
+	case change, open := <-changes:
+		assert(t, open, "Change channel closed")
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for a change")
+	}
+	return generichold.Change[T]{}
+}
+
+func watchOptions() badgerhold.Options {
+	opt := testOptions()
+	opt.NumVersionsToKeep = 2
+	return opt
+}
+
+func TestWatch(t *testing.T) {
+	testWrapWithOpt(t, watchOptions(), func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		name := generichold.Field[KeyedItem, string]("Name")
+		changes, err := store.Watch(ctx, generichold.Where(name.HasPrefix("watched")))
+		ok(t, err)
+
+		key, err := store.InsertNext(&KeyedItem{Name: "watched"})
+		ok(t, err)
+		_, err = store.InsertNext(&KeyedItem{Name: "ignored"})
+		ok(t, err)
+
+		change := nextChange(t, changes)
+		equals(t, generichold.ChangeInsert, change.Type)
+		assert(t, change.Old == nil, "Insert has an old value %v", change.Old)
+		equals(t, KeyedItem{ID: key, Name: "watched"}, *change.New)
+
+		ok(t, store.Update(key, &KeyedItem{Name: "watched again"}))
+		change = nextChange(t, changes)
+		equals(t, generichold.ChangeUpdate, change.Type)
+		equals(t, "watched", change.Old.Name)
+		equals(t, "watched again", change.New.Name)
+
+		// moving out of the query is still reported
+		ok(t, store.Update(key, &KeyedItem{Name: "moved"}))
+		change = nextChange(t, changes)
+		equals(t, generichold.ChangeUpdate, change.Type)
+		equals(t, "moved", change.New.Name)
+
+		ok(t, store.Update(key, &KeyedItem{Name: "watched"}))
+		nextChange(t, changes)
+
+		ok(t, store.Delete(key))
+		change = nextChange(t, changes)
+		equals(t, generichold.ChangeDelete, change.Type)
+		equals(t, KeyedItem{ID: key, Name: "watched"}, *change.Old)
+		assert(t, change.New == nil, "Delete has a new value %v", change.New)
+
+		cancel()
+		for range changes {
+		}
+	})
+}
+
+func TestWatchNeedsVersions(t *testing.T) {
+	testWrap(t, func(bh *badgerhold.Store, t *testing.T) {
+		_, err := generichold.Open[KeyedItem](bh).Watch(context.Background(), nil)
+		equals(t, generichold.ErrVersionsNotKept, err)
+	})
+}
+
+func TestWatchError(t *testing.T) {
+	testWrapWithOpt(t, watchOptions(), func(bh *badgerhold.Store, t *testing.T) {
+		store := generichold.OpenKeyed[uint64, KeyedItem](bh)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		changes, err := store.Watch(ctx, nil)
+		ok(t, err)
+
+		// a record that does not decode ends the watch with its error
+		ok(t, bh.Badger().Update(func(tx *badger.Txn) error {
+			return tx.Set([]byte("bh_KeyedItem:garbage"), []byte("garbage"))
+		}))
+		change := nextChange(t, changes)
+		assert(t, change.Err != nil, "Watch did not report the decode error")
+
+		_, open := <-changes
+		assert(t, !open, "Change channel left open after an error")
+	})
+}
@@ -0,0 +1,316 @@
+// Copyright 2025 Lane Shukhov. All rights reserved.
+// Use of this source code is governed by the MIT license
+// that can be found in the LICENSE file.
+
+package generichold
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/timshannon/badgerhold/v4"
+)
+
+type writeKind int
+
+const (
+	writeInsert writeKind = iota
+	writeUpdate
+	writeUpsert
+)
+
+// beforeWrite runs the hooks and validators of data and sets the fields generichold manages on it
+// before it is written under key, reading the stored record when they depend on it. Once badgerhold
+// wrote data, written finishes the write, restore puts data back the way it came for when it fails.
+func (s *store[T]) beforeWrite(tx *badger.Txn, key any, data *T, kind writeKind) (written func() error, restore func(), err error) {
+	saved := *data
+	restore = func() { *data = saved }
+
+	var stored *T
+	if kind != writeInsert && s.readsStored(kind) {
+		stored = new(T)
+		err := s.store.TxGet(tx, key, stored)
+		if err == badgerhold.ErrNotFound && kind == writeUpsert {
+			stored = nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+	if stored != nil {
+		s.loaded(stored)
+	}
+
+	inserted := kind == writeInsert || (kind == writeUpsert && stored == nil)
+	if inserted {
+		err = s.beforeInsert(data)
+	} else {
+		err = s.beforeUpdate(data, stored)
+	}
+	if err == nil {
+		err = s.validate(data)
+	}
+	if err == nil {
+		err = s.prepare(key, data, stored)
+	}
+	if err == nil {
+		err = s.checkUnique(tx, key, data, inserted)
+	}
+	if err != nil {
+		restore()
+		return nil, nil, err
+	}
+
+	written = func() error {
+		err := s.indexUnique(tx, key, data, stored)
+		if err == nil {
+			err = s.indexComposite(tx, key, data, stored)
+		}
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			return s.keepRevision(tx, key, stored)
+		}
+		s.afterInsert(data)
+		return nil
+	}
+	return written, restore, nil
+}
+
+// readsStored reports whether writing over a stored record needs to read it first
+func (s *store[T]) readsStored(kind writeKind) bool {
+	if s.versionIndex != nil || s.createdIndex != nil || s.hooks.beforeUpdate || s.options.history ||
+		s.indexed() {
+		return true
+	}
+	// an upsert has to know whether it inserts
+	return kind == writeUpsert && (s.hooks.beforeInsert || s.hooks.afterInsert)
+}
+
+// beforeMatchWrite is beforeWrite for a record of UpdateMatching changed by its update func,
+// stored is the record as it was read
+func (s *store[T]) beforeMatchWrite(data, stored *T) error {
+	err := s.beforeUpdate(data, stored)
+	if err == nil {
+		err = s.validate(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.versionIndex != nil {
+		// the record was just read, its version can't be stale
+		s.version(data).SetUint(s.version(stored).Uint())
+	}
+	return s.prepare(nil, data, stored)
+}
+
+// prepare sets the fields generichold manages on data, written over stored or inserted when stored is nil.
+// An inserted record keeps a createdAt it already has, an updated one the createdAt of stored.
+func (s *store[T]) prepare(key any, data, stored *T) error {
+	err := s.nextVersion(key, data, stored)
+	if err != nil {
+		return err
+	}
+
+	if s.createdIndex == nil && s.updatedIndex == nil {
+		return nil
+	}
+
+	now := reflect.ValueOf(s.options.clock())
+	if s.createdIndex != nil {
+		created := reflect.ValueOf(data).Elem().FieldByIndex(s.createdIndex)
+		if stored != nil {
+			created.Set(reflect.ValueOf(stored).Elem().FieldByIndex(s.createdIndex))
+		} else if created.IsZero() {
+			created.Set(now)
+		}
+	}
+	if s.updatedIndex != nil {
+		reflect.ValueOf(data).Elem().FieldByIndex(s.updatedIndex).Set(now)
+	}
+	return nil
+}
+
+// deleteEach deletes the records matching query one by one with TxDelete, for when deleting a record
+// takes more than badgerhold does. It reads their keys from the key field, see deleteKeyless for a T without one.
+func (s *store[T]) deleteEach(tx *badger.Txn, query *badgerhold.Query) error {
+	if s.keyIndex == nil {
+		return s.deleteKeyless(tx, query)
+	}
+
+	// the matches are collected first, badgerhold is still iterating over them in the callback
+	var keys []any
+	err := s.forEach(tx, query, func(record *T) error {
+		keys = append(keys, s.recordKey(record))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		err = s.TxDelete(tx, key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteKeyless is deleteEach for a T without key field, whose matches badgerhold tells no keys of:
+// TxDeleteMatching deletes them, the badger keys it removed are theirs, and their records are read back
+// from the versions older than tx. Deleting hides the versions at the read timestamp of tx, which may be
+// writes of tx itself, so those are read beforehand.
+func (s *store[T]) deleteKeyless(tx *badger.Txn, query *badgerhold.Query) error {
+	eachKey := func(fn func(item *badger.Item) error) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = s.prefix
+		iter := tx.NewIterator(opts)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			if err := fn(iter.Item()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	gone := make(map[string][]byte)
+	err := eachKey(func(item *badger.Item) error {
+		var value []byte
+		var err error
+		if item.Version() == tx.ReadTs() {
+			value, err = item.ValueCopy(nil)
+		}
+		gone[string(item.Key())] = value
+		return err
+	})
+	if err == nil {
+		err = s.store.TxDeleteMatching(tx, s.zeroValue(), query)
+	}
+	if err == nil {
+		err = eachKey(func(item *badger.Item) error {
+			delete(gone, string(item.Key()))
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := slices.Sorted(maps.Keys(gone))
+	for _, key := range keys {
+		value := gone[key]
+		if value == nil {
+			value, err = olderValue(tx, []byte(key))
+			if err != nil {
+				return err
+			}
+		}
+
+		encoded := rawKey(key[len(s.prefix):])
+		record, err := s.decodeRecord(encoded, value)
+		if err == nil {
+			err = s.retire(tx, encoded, record)
+		}
+		if err == nil && s.deletedIndex != nil {
+			err = s.keepDeleted(tx, encoded, record)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// olderValue returns the value of the newest version of key older than tx
+func olderValue(tx *badger.Txn, key []byte) ([]byte, error) {
+	iter := tx.NewIterator(badger.IteratorOptions{AllVersions: true, Prefix: key})
+	defer iter.Close()
+
+	for iter.Seek(key); iter.ValidForPrefix(key); iter.Next() {
+		item := iter.Item()
+		if !bytes.Equal(item.Key(), key) || item.Version() >= tx.ReadTs() {
+			continue
+		}
+		if item.IsDeletedOrExpired() {
+			break
+		}
+		return item.ValueCopy(nil)
+	}
+	return nil, badgerhold.ErrNotFound
+}
+
+// retire does what goes along with deleting record, stored under key: it runs the BeforeDelete hook,
+// keeps the record in the history and drops its index entries
+func (s *store[T]) retire(tx *badger.Txn, key any, record *T) error {
+	err := s.beforeDelete(record)
+	if err == nil {
+		err = s.keepRevision(tx, key, record)
+	}
+	if err == nil {
+		err = s.dropIndexes(tx, key, record)
+	}
+	return err
+}
+
+// updateEach runs update on the records matching query and writes them one by one with TxUpdate,
+// for when writing a record needs its key, which badgerhold does not pass to the update func of UpdateMatching.
+// It needs their keys from the key field.
+func (s *store[T]) updateEach(tx *badger.Txn, query *badgerhold.Query, update func(record *T) error) error {
+	if s.keyIndex == nil {
+		return ErrNoKeyField
+	}
+
+	var keys []any
+	err := s.forEach(tx, query, func(record *T) error {
+		keys = append(keys, s.recordKey(record))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		record, err := s.get(tx, key)
+		if err == nil {
+			err = update(&record)
+		}
+		if err == nil {
+			err = s.TxUpdate(tx, key, &record)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordKey returns the value of the key field of record
+func (s *store[T]) recordKey(record *T) any {
+	return reflect.ValueOf(record).Elem().FieldByIndex(s.keyIndex).Interface()
+}
+
+// managedField returns the index of the field of tp tagged `generichold:"<name>"` of type fieldType,
+// nil if there is none
+func managedField(tp reflect.Type, name string, fieldType reflect.Type) ([]int, error) {
+	field, ok := taggedField(tp, name)
+	if !ok {
+		return nil, nil
+	}
+
+	if field.Type != fieldType {
+		return nil, errors.New("the " + name + " field " + field.Name + " of " + tp.Name() + " must be a " + fieldType.String())
+	}
+	return field.Index, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})